@@ -0,0 +1,593 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// account is the Go struct the host exposes to the scripts. Every script
+// runs the same loop over it: deposit i, withdraw the limit once the
+// balance passes it, and count the round in Ops by writing the field.
+type account struct {
+	Balance int64
+	Limit   int64
+	Ops     int64
+}
+
+func (a *account) Deposit(n int64) int64 {
+	a.Balance += n
+	return a.Balance
+}
+
+func (a *account) Withdraw(n int64) int64 {
+	if n > a.Balance {
+		return 0
+	}
+	a.Balance -= n
+	return n
+}
+
+const (
+	accountRounds = 100
+	accountLimit  = 1000
+)
+
+// expectedAccount is the account after the scripts' loop, run in Go.
+func expectedAccount() account {
+	a := account{Limit: accountLimit}
+	for i := int64(1); i <= accountRounds; i++ {
+		a.Deposit(i)
+		if a.Balance > a.Limit {
+			a.Withdraw(a.Limit)
+		}
+		a.Ops = a.Ops + 1
+	}
+	return a
+}
+
+// accountBinding is one way of exposing an account to an engine. load
+// binds acct and returns a function running the loop once, which returns
+// the final balance as seen by the script.
+type accountBinding struct {
+	name string
+	load func(acct *account) (func() (int64, error), error)
+}
+
+// benchmarkBindings runs one sub-benchmark per binding. reflect bindings
+// resolve every field and method by Go reflection when the script touches
+// it, manual ones are accessors written by hand for account. Every call
+// starts from a fresh account and checks both the balance returned and
+// the Ops written by the script.
+func benchmarkBindings(t *testing.B, bindings ...accountBinding) {
+	want := expectedAccount()
+	for _, b := range bindings {
+		acct := &account{}
+		run, err := b.load(acct)
+		MustSuccess(t, err)
+		subBenchmark(t, b.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				*acct = account{Limit: accountLimit}
+				balance, err := run()
+				MustSuccess(t, err)
+				MustEqualInt64(t, want.Balance, balance)
+				MustEqualInt64(t, want.Ops, acct.Ops)
+			}
+		})
+	}
+}
+
+// reflectMember resolves name on the struct ptr points to: an int64 field,
+// or else a method. It is the generic binding behind the reflect variants
+// of glisp, Lua and zygo; goja's ToValue does the same inside the engine.
+func reflectMember(ptr reflect.Value, name string) (field, method reflect.Value, err error) {
+	if f := ptr.Elem().FieldByName(name); f.IsValid() && f.Kind() == reflect.Int64 {
+		return f, reflect.Value{}, nil
+	}
+	if m := ptr.MethodByName(name); m.IsValid() {
+		return reflect.Value{}, m, nil
+	}
+	return reflect.Value{}, reflect.Value{}, fmt.Errorf("%s has no field or method %s", ptr.Type().Elem().Name(), name)
+}
+
+// callReflect calls a method returning an int64 with int64 arguments.
+func callReflect(method reflect.Value, args []int64) (int64, error) {
+	if method.Type().NumIn() != len(args) {
+		return 0, fmt.Errorf("method takes %d arguments, got %d", method.Type().NumIn(), len(args))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		in[i] = reflect.ValueOf(a)
+	}
+	return method.Call(in)[0].Int(), nil
+}
+
+// glispAccount exposes an account to glisp. The colon syntax of records,
+// (:Name a args...), goes through Explain, which resolves Name by
+// reflection: a field reads without arguments and is set by one, and a
+// method is called with them.
+type glispAccount struct {
+	acct *account
+}
+
+func (a glispAccount) SexpString() string {
+	return fmt.Sprintf("#account%+v", *a.acct)
+}
+
+func (a glispAccount) Explain(env *glisp.Environment, name string, args glisp.Args) (glisp.Sexp, error) {
+	field, method, err := reflectMember(reflect.ValueOf(a.acct), name)
+	if err != nil {
+		return glisp.SexpNull, err
+	}
+	ints := make([]int64, args.Len())
+	for i := range ints {
+		n, ok := args.Get(i).(glisp.SexpInt)
+		if !ok {
+			return glisp.SexpNull, fmt.Errorf("%s takes integers", name)
+		}
+		ints[i] = n.ToInt64()
+	}
+	switch {
+	case field.IsValid() && len(ints) == 0:
+		return glisp.NewSexpInt64(field.Int()), nil
+	case field.IsValid() && len(ints) == 1:
+		field.SetInt(ints[0])
+		return args.Get(0), nil
+	case field.IsValid():
+		return glisp.WrongNumberArguments(name, args.Len(), 0, 1)
+	}
+	v, err := callReflect(method, ints)
+	if err != nil {
+		return glisp.SexpNull, err
+	}
+	return glisp.NewSexpInt64(v), nil
+}
+
+// glispAccountFunctions are the hand-written accessors of the manual
+// binding. Each takes the account first and ints integers after it.
+var glispAccountFunctions = map[string]struct {
+	ints int
+	call func(a *account, args []int64) int64
+}{
+	"account/balance":  {0, func(a *account, _ []int64) int64 { return a.Balance }},
+	"account/limit":    {0, func(a *account, _ []int64) int64 { return a.Limit }},
+	"account/ops":      {0, func(a *account, _ []int64) int64 { return a.Ops }},
+	"account/set-ops!": {1, func(a *account, args []int64) int64 { a.Ops = args[0]; return a.Ops }},
+	"account/deposit":  {1, func(a *account, args []int64) int64 { return a.Deposit(args[0]) }},
+	"account/withdraw": {1, func(a *account, args []int64) int64 { return a.Withdraw(args[0]) }},
+}
+
+func benchStructBinding_glisp(t *testing.B) {
+	load := func(src string, register func(vm *glisp.Environment)) func(acct *account) (func() (int64, error), error) {
+		return func(acct *account) (func() (int64, error), error) {
+			vm := glisp.New()
+			ext.ImportCoreUtils(vm)
+			if register != nil {
+				register(vm)
+			}
+			if err := vm.SourceStream(bytes.NewBufferString(src)); err != nil {
+				return nil, err
+			}
+			args := glisp.MakeArgs(glispAccount{acct}, glisp.NewSexpInt64(accountRounds))
+			return func() (int64, error) {
+				v, err := vm.ApplyByName("run", args)
+				if err != nil {
+					return 0, err
+				}
+				n, ok := v.(glisp.SexpInt)
+				if !ok {
+					return 0, fmt.Errorf("run returned %s", v.SexpString())
+				}
+				return n.ToInt64(), nil
+			}, nil
+		}
+	}
+	benchmarkBindings(t,
+		accountBinding{"reflect", load(`
+(defn step [a i n]
+  (when (<= i n)
+    (:Deposit a i)
+    (when (> (:Balance a) (:Limit a))
+      (:Withdraw a (:Limit a)))
+    (:Ops a (+ (:Ops a) 1))
+    (step a (+ i 1) n)))
+
+(defn run [a n]
+  (step a 1 n)
+  (:Balance a))
+`, nil)},
+		accountBinding{"manual", load(`
+(defn step [a i n]
+  (when (<= i n)
+    (account/deposit a i)
+    (when (> (account/balance a) (account/limit a))
+      (account/withdraw a (account/limit a)))
+    (account/set-ops! a (+ (account/ops a) 1))
+    (step a (+ i 1) n)))
+
+(defn run [a n]
+  (step a 1 n)
+  (account/balance a))
+`, func(vm *glisp.Environment) {
+			for name, f := range glispAccountFunctions {
+				name, f := name, f
+				vm.AddFunction(name, func(env *glisp.Environment, args glisp.Args) (glisp.Sexp, error) {
+					if args.Len() != f.ints+1 {
+						return glisp.WrongNumberArguments(name, args.Len(), f.ints+1)
+					}
+					a, ok := args.Get(0).(glispAccount)
+					if !ok {
+						return glisp.SexpNull, fmt.Errorf("%s takes an account", name)
+					}
+					ints := make([]int64, args.Len()-1)
+					for i := range ints {
+						n, ok := args.Get(i + 1).(glisp.SexpInt)
+						if !ok {
+							return glisp.SexpNull, fmt.Errorf("%s takes integers", name)
+						}
+						ints[i] = n.ToInt64()
+					}
+					return glisp.NewSexpInt64(f.call(a.acct, ints)), nil
+				})
+			}
+		})},
+	)
+}
+
+func benchStructBinding_goja(t *testing.B) {
+	const SCRIPT = `
+function run(a, n) {
+	for (let i = 1; i <= n; i++) {
+		a.Deposit(i);
+		if (a.Balance > a.Limit) {
+			a.Withdraw(a.Limit);
+		}
+		a.Ops = a.Ops + 1;
+	}
+	return a.Balance;
+}
+`
+	load := func(bind func(vm *goja.Runtime, acct *account) goja.Value) func(acct *account) (func() (int64, error), error) {
+		return func(acct *account) (func() (int64, error), error) {
+			vm := goja.New()
+			if _, err := vm.RunString(SCRIPT); err != nil {
+				return nil, err
+			}
+			f, ok := goja.AssertFunction(vm.Get("run"))
+			if !ok {
+				return nil, fmt.Errorf("run is not a function")
+			}
+			a, n := bind(vm, acct), vm.ToValue(accountRounds)
+			return func() (int64, error) {
+				res, err := f(goja.Undefined(), a, n)
+				if err != nil {
+					return 0, err
+				}
+				return res.ToInteger(), nil
+			}, nil
+		}
+	}
+	benchmarkBindings(t,
+		accountBinding{"reflect", load(func(vm *goja.Runtime, acct *account) goja.Value {
+			return vm.ToValue(acct)
+		})},
+		accountBinding{"manual", load(func(vm *goja.Runtime, acct *account) goja.Value {
+			obj := vm.NewObject()
+			getter := func(field *int64) goja.Value {
+				return vm.ToValue(func(goja.FunctionCall) goja.Value { return vm.ToValue(*field) })
+			}
+			obj.DefineAccessorProperty("Balance", getter(&acct.Balance), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+			obj.DefineAccessorProperty("Limit", getter(&acct.Limit), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+			obj.DefineAccessorProperty("Ops", getter(&acct.Ops), vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				acct.Ops = call.Argument(0).ToInteger()
+				return goja.Undefined()
+			}), goja.FLAG_FALSE, goja.FLAG_TRUE)
+			obj.Set("Deposit", func(call goja.FunctionCall) goja.Value {
+				return vm.ToValue(acct.Deposit(call.Argument(0).ToInteger()))
+			})
+			obj.Set("Withdraw", func(call goja.FunctionCall) goja.Value {
+				return vm.ToValue(acct.Withdraw(call.Argument(0).ToInteger()))
+			})
+			return obj
+		})},
+	)
+}
+
+// luaAccountMethods are the methods of the manual Lua binding, called as
+// a:Deposit(n) with the userdata first.
+var luaAccountMethods = []lua.RegistryFunction{
+	{Name: "Deposit", Function: func(l *lua.State) int {
+		a := lua.CheckUserData(l, 1, "account").(*account)
+		l.PushInteger(int(a.Deposit(int64(lua.CheckInteger(l, 2)))))
+		return 1
+	}},
+	{Name: "Withdraw", Function: func(l *lua.State) int {
+		a := lua.CheckUserData(l, 1, "account").(*account)
+		l.PushInteger(int(a.Withdraw(int64(lua.CheckInteger(l, 2)))))
+		return 1
+	}},
+}
+
+func benchStructBinding_lua(t *testing.B) {
+	const SCRIPT = `
+function run(a, n)
+  for i = 1, n do
+    a:Deposit(i)
+    if a.Balance > a.Limit then
+      a:Withdraw(a.Limit)
+    end
+    a.Ops = a.Ops + 1
+  end
+  return a.Balance
+end
+`
+	// load binds the account as userdata with a metatable whose __index
+	// and __newindex come from metatable, which receives the metatable on
+	// top of the stack.
+	load := func(metatable func(l *lua.State)) func(acct *account) (func() (int64, error), error) {
+		return func(acct *account) (func() (int64, error), error) {
+			l := lua.NewState()
+			lua.OpenLibraries(l)
+			if err := lua.DoString(l, SCRIPT); err != nil {
+				return nil, err
+			}
+			lua.NewMetaTable(l, "account")
+			metatable(l)
+			l.Pop(1)
+			// the userdata stays at the bottom of the stack for every call.
+			l.PushUserData(acct)
+			lua.SetMetaTableNamed(l, "account")
+			return func() (int64, error) {
+				l.Global("run")
+				l.PushValue(1)
+				l.PushInteger(accountRounds)
+				if err := l.ProtectedCall(2, 1, 0); err != nil {
+					l.Pop(1)
+					return 0, err
+				}
+				defer l.Pop(1)
+				n, _ := l.ToInteger(-1)
+				return int64(n), nil
+			}, nil
+		}
+	}
+	benchmarkBindings(t,
+		accountBinding{"reflect", load(func(l *lua.State) {
+			l.PushGoFunction(func(l *lua.State) int {
+				a := lua.CheckUserData(l, 1, "account").(*account)
+				field, method, err := reflectMember(reflect.ValueOf(a), lua.CheckString(l, 2))
+				if err != nil {
+					lua.Errorf(l, "%s", err.Error())
+				}
+				if field.IsValid() {
+					l.PushInteger(int(field.Int()))
+					return 1
+				}
+				l.PushGoFunction(func(l *lua.State) int {
+					args := make([]int64, l.Top()-1)
+					for i := range args {
+						args[i] = int64(lua.CheckInteger(l, i+2))
+					}
+					v, err := callReflect(method, args)
+					if err != nil {
+						lua.Errorf(l, "%s", err.Error())
+					}
+					l.PushInteger(int(v))
+					return 1
+				})
+				return 1
+			})
+			l.SetField(-2, "__index")
+			l.PushGoFunction(func(l *lua.State) int {
+				a := lua.CheckUserData(l, 1, "account").(*account)
+				field, _, err := reflectMember(reflect.ValueOf(a), lua.CheckString(l, 2))
+				if err == nil && !field.IsValid() {
+					err = fmt.Errorf("cannot assign to method %s", lua.CheckString(l, 2))
+				}
+				if err != nil {
+					lua.Errorf(l, "%s", err.Error())
+				}
+				field.SetInt(int64(lua.CheckInteger(l, 3)))
+				return 0
+			})
+			l.SetField(-2, "__newindex")
+		})},
+		accountBinding{"manual", load(func(l *lua.State) {
+			// __index looks fields up in a switch and methods in a table
+			// held as its upvalue.
+			l.NewTable()
+			lua.SetFunctions(l, luaAccountMethods, 0)
+			l.PushGoClosure(func(l *lua.State) int {
+				a := lua.CheckUserData(l, 1, "account").(*account)
+				switch key := lua.CheckString(l, 2); key {
+				case "Balance":
+					l.PushInteger(int(a.Balance))
+				case "Limit":
+					l.PushInteger(int(a.Limit))
+				case "Ops":
+					l.PushInteger(int(a.Ops))
+				default:
+					l.Field(lua.UpValueIndex(1), key)
+				}
+				return 1
+			}, 1)
+			l.SetField(-2, "__index")
+			l.PushGoFunction(func(l *lua.State) int {
+				a := lua.CheckUserData(l, 1, "account").(*account)
+				if key := lua.CheckString(l, 2); key != "Ops" {
+					lua.Errorf(l, "cannot assign to %s", key)
+				}
+				a.Ops = int64(lua.CheckInteger(l, 3))
+				return 0
+			})
+			l.SetField(-2, "__newindex")
+		})},
+	)
+}
+
+// zygoAccount exposes an account to zygo as an opaque value, which only
+// the registered functions of a binding can look into.
+type zygoAccount struct {
+	acct *account
+}
+
+func (a *zygoAccount) SexpString(ps *zygo.PrintState) string {
+	return fmt.Sprintf("#account%+v", *a.acct)
+}
+
+func (a *zygoAccount) Type() *zygo.RegisteredType { return nil }
+
+// zygoAccountArgs checks that args are an account followed by integers.
+func zygoAccountArgs(name string, args []zygo.Sexp) (*account, []int64, error) {
+	if len(args) == 0 {
+		return nil, nil, zygo.WrongNargs
+	}
+	a, ok := args[0].(*zygoAccount)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s takes an account", name)
+	}
+	ints := make([]int64, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		n, ok := arg.(*zygo.SexpInt)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s takes integers", name)
+		}
+		ints = append(ints, n.Val)
+	}
+	return a.acct, ints, nil
+}
+
+func benchStructBinding_zygo(t *testing.B) {
+	load := func(src string, functions map[string]func(a *account, name string, args []int64) (int64, error)) func(acct *account) (func() (int64, error), error) {
+		return func(acct *account) (func() (int64, error), error) {
+			env := zygo.NewZlisp()
+			for name, f := range functions {
+				f := f
+				env.AddFunction(name, func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+					// the member name of the reflect functions is a string
+					// after the account, passed on to f.
+					member := ""
+					if len(args) > 1 {
+						if s, ok := args[1].(*zygo.SexpStr); ok {
+							member = s.S
+							args = append(args[:1:1], args[2:]...)
+						}
+					}
+					a, ints, err := zygoAccountArgs(name, args)
+					if err != nil {
+						return zygo.SexpNull, err
+					}
+					v, err := f(a, member, ints)
+					if err != nil {
+						return zygo.SexpNull, err
+					}
+					return &zygo.SexpInt{Val: v}, nil
+				})
+			}
+			if _, err := env.EvalString(src); err != nil {
+				return nil, err
+			}
+			v, ok := env.FindObject("run")
+			if !ok {
+				return nil, fmt.Errorf("run not found")
+			}
+			fn := v.(*zygo.SexpFunction)
+			args := []zygo.Sexp{&zygoAccount{acct}, &zygo.SexpInt{Val: accountRounds}}
+			return func() (int64, error) {
+				res, err := env.Apply(fn, args)
+				if err != nil {
+					return 0, err
+				}
+				n, ok := res.(*zygo.SexpInt)
+				if !ok {
+					return 0, fmt.Errorf("run returned %s", res.SexpString(nil))
+				}
+				return n.Val, nil
+			}, nil
+		}
+	}
+	// ints wraps a manual accessor taking n integers after the account.
+	ints := func(n int, f func(a *account, args []int64) int64) func(a *account, name string, args []int64) (int64, error) {
+		return func(a *account, _ string, args []int64) (int64, error) {
+			if len(args) != n {
+				return 0, zygo.WrongNargs
+			}
+			return f(a, args), nil
+		}
+	}
+	field := func(a *account, name string) (reflect.Value, error) {
+		f, _, err := reflectMember(reflect.ValueOf(a), name)
+		if err == nil && !f.IsValid() {
+			err = fmt.Errorf("%s is not a field", name)
+		}
+		return f, err
+	}
+	benchmarkBindings(t,
+		accountBinding{"reflect", load(`
+(defn run [a n]
+  (for [(def i 1) (<= i n) (set i (+ i 1))]
+    (callMethod a "Deposit" i)
+    (cond (> (getField a "Balance") (getField a "Limit"))
+          (callMethod a "Withdraw" (getField a "Limit"))
+          0)
+    (setField a "Ops" (+ (getField a "Ops") 1)))
+  (getField a "Balance"))
+`, map[string]func(a *account, name string, args []int64) (int64, error){
+			"getField": func(a *account, name string, args []int64) (int64, error) {
+				if len(args) != 0 {
+					return 0, zygo.WrongNargs
+				}
+				f, err := field(a, name)
+				if err != nil {
+					return 0, err
+				}
+				return f.Int(), nil
+			},
+			"setField": func(a *account, name string, args []int64) (int64, error) {
+				f, err := field(a, name)
+				if err != nil {
+					return 0, err
+				}
+				if len(args) != 1 {
+					return 0, zygo.WrongNargs
+				}
+				f.SetInt(args[0])
+				return args[0], nil
+			},
+			"callMethod": func(a *account, name string, args []int64) (int64, error) {
+				_, m, err := reflectMember(reflect.ValueOf(a), name)
+				if err == nil && !m.IsValid() {
+					err = fmt.Errorf("%s is not a method", name)
+				}
+				if err != nil {
+					return 0, err
+				}
+				return callReflect(m, args)
+			},
+		})},
+		accountBinding{"manual", load(`
+(defn run [a n]
+  (for [(def i 1) (<= i n) (set i (+ i 1))]
+    (accountDeposit a i)
+    (cond (> (accountBalance a) (accountLimit a))
+          (accountWithdraw a (accountLimit a))
+          0)
+    (setAccountOps a (+ (accountOps a) 1)))
+  (accountBalance a))
+`, map[string]func(a *account, name string, args []int64) (int64, error){
+			"accountBalance":  ints(0, func(a *account, _ []int64) int64 { return a.Balance }),
+			"accountLimit":    ints(0, func(a *account, _ []int64) int64 { return a.Limit }),
+			"accountOps":      ints(0, func(a *account, _ []int64) int64 { return a.Ops }),
+			"setAccountOps":   ints(1, func(a *account, args []int64) int64 { a.Ops = args[0]; return a.Ops }),
+			"accountDeposit":  ints(1, func(a *account, args []int64) int64 { return a.Deposit(args[0]) }),
+			"accountWithdraw": ints(1, func(a *account, args []int64) int64 { return a.Withdraw(args[0]) }),
+		})},
+	)
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+	gopherlua "github.com/yuin/gopher-lua"
+)
+
+// Every engine gets two measurements. "stop" starts a script that never
+// finishes on its own, fires the engine's cancellation after stopDelay and
+// reports how long the call took to return after that as stop-ns/op.
+// "<workload>/plain" and "<workload>/cancellable" run each of
+// cancelWorkloads without and with a per-call cancellation deadline armed,
+// which is the price every rule pays for being killable.
+//
+// glisp and zygo cannot interrupt a running script from Go. Their loops
+// poll a registered cancelled? builtin instead, so only scripts written to
+// poll can be stopped; a runaway rule that never calls it runs forever.
+// Their cancellable variants poll on every recursion of factorial and once
+// on entry to the other workloads, which have no loop.
+const (
+	stopDelay     = 200 * time.Microsecond
+	cancelTimeout = time.Second
+)
+
+// cancelWorkload is an existing workload rerun by Cancel. Every engine's
+// script defines fn, taking arg (an int or a string) and returning expect.
+type cancelWorkload struct {
+	name   string
+	fn     string
+	arg    interface{}
+	expect string
+}
+
+var cancelWorkloads = []cancelWorkload{
+	{"factorial", "factorial", 10, "3628800"},
+	{"complex-condition", "complex_condition", 15, "medium"},
+	{"hash-access", "get_from_hash", "key5", "value5"},
+	{"json-modify", "parse_and_modify", `{"name": "John", "age": 30, "city": "New York"}`, "new_name"},
+}
+
+// cancelEngine adapts benchCancelWorkloads to one engine.
+type cancelEngine struct {
+	// call invokes the script function fn with arg and returns its result
+	// as a string.
+	call func(t *testing.B, fn string, arg interface{}) string
+	// suffix names the variant of every function that polls cancelled?,
+	// for the engines that cannot interrupt a script from Go.
+	suffix string
+	// arm starts the cancellation deadline of one call and returns the
+	// function that disarms it.
+	arm func() (disarm func())
+	// setup, if set, runs before the cancellable loop and returns the
+	// function undoing it, as for Lua's count hook.
+	setup func() (teardown func())
+}
+
+// benchCancelWorkloads runs the plain and cancellable rows of every
+// cancelWorkload.
+func benchCancelWorkloads(t *testing.B, e cancelEngine) {
+	for _, w := range cancelWorkloads {
+		subBenchmark(t, w.name+"/plain", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				MustEqual(t, w.expect, e.call(t, w.fn, w.arg))
+			}
+		})
+		subBenchmark(t, w.name+"/cancellable", func(t *testing.B) {
+			if e.setup != nil {
+				defer e.setup()()
+			}
+			for i := 0; i < t.N; i++ {
+				disarm := e.arm()
+				res := e.call(t, w.fn+e.suffix, w.arg)
+				disarm()
+				MustEqual(t, w.expect, res)
+			}
+		})
+	}
+}
+
+// cancelHashScript is the ten-key table of HashAccess in glisp and zygo.
+const cancelHashScript = `
+(def m (hash
+    "key1" "value1"
+    "key2" "value2"
+    "key3" "value3"
+    "key4" "value4"
+    "key5" "value5"
+    "key6" "value6"
+    "key7" "value7"
+    "key8" "value8"
+    "key9" "value9"
+    "key10" "value10"))
+`
+
+// luaHashScript is the ten-key table of HashAccess in Lua.
+const luaHashScript = `
+local m = {
+    ["key1"] = "value1",
+    ["key2"] = "value2",
+    ["key3"] = "value3",
+    ["key4"] = "value4",
+    ["key5"] = "value5",
+    ["key6"] = "value6",
+    ["key7"] = "value7",
+    ["key8"] = "value8",
+    ["key9"] = "value9",
+    ["key10"] = "value10"
+}
+
+function get_from_hash(key)
+    return m[key]
+end
+`
+
+// modifyJSON is the Go side of JSONParseAndModify in Lua, which has no JSON
+// decoder of its own.
+func modifyJSON(s string) (string, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		return "", err
+	}
+	data["name"] = "new_name"
+	return data["name"].(string), nil
+}
+
+// benchStop runs t.N stop iterations. reset prepares the engine for the
+// next run, stop is the cancellation fired from the timer goroutine and run
+// executes the endless script, returning once it has been stopped.
+func benchStop(t *testing.B, reset, stop, run func()) {
+	var total time.Duration
+	for i := 0; i < t.N; i++ {
+		reset()
+		var firedAt atomic.Int64
+		timer := time.AfterFunc(stopDelay, func() {
+			firedAt.Store(time.Now().UnixNano())
+			stop()
+		})
+		run()
+		returned := time.Now()
+		timer.Stop()
+		MustTrue(t, firedAt.Load() != 0)
+		total += returned.Sub(time.Unix(0, firedAt.Load()))
+	}
+	reportMetric(t, float64(total.Nanoseconds())/float64(t.N), "stop-ns/op")
+}
+
+func benchCancel_glisp(t *testing.B) {
+	var cancelled atomic.Bool
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	ext.ImportJSON(vm)
+	vm.AddFunction("cancelled?", func(env *glisp.Environment, args glisp.Args) (glisp.Sexp, error) {
+		return glisp.SexpBool(cancelled.Load()), nil
+	})
+	err := vm.SourceStream(bytes.NewBufferString(`
+(defn spin [n] (cond (cancelled?) n (spin (+ n 1))))
+
+(defn factorial [n] (cond (= 1 n) n (* n (factorial (- n 1)))))
+
+(defn factorial_cancellable [n]
+  (cond (cancelled?) 0
+        (= 1 n) n
+        (* n (factorial_cancellable (- n 1)))))
+
+(defn complex_condition [n]
+  (cond (<= 0 n 10) "low"
+        (<= 10 n 20) "medium"
+        (<= 20 n 30) "high"
+        "unknown"))
+
+(defn complex_condition_cancellable [n]
+  (cond (cancelled?) ""
+        (<= 0 n 10) "low"
+        (<= 10 n 20) "medium"
+        (<= 20 n 30) "high"
+        "unknown"))
+` + cancelHashScript + `
+(defn get_from_hash [key] (hget m key))
+
+(defn get_from_hash_cancellable [key] (cond (cancelled?) "" (hget m key)))
+
+(defn parse_and_modify [json_str]
+  (let [data (json/parse json_str)]
+    (hset! data "name" "new_name")
+    (hget data "name")))
+
+(defn parse_and_modify_cancellable [json_str]
+  (cond (cancelled?) ""
+        (let [data (json/parse json_str)]
+          (hset! data "name" "new_name")
+          (hget data "name"))))
+`))
+	MustSuccess(t, err)
+	subBenchmark(t, "stop", func(t *testing.B) {
+		benchStop(t, func() { cancelled.Store(false) }, func() { cancelled.Store(true) }, func() {
+			v, err := vm.ApplyByName("spin", glisp.MakeArgs(glisp.NewSexpInt(0)))
+			MustSuccess(t, err)
+			_, ok := v.(glisp.SexpInt)
+			MustTrue(t, ok)
+		})
+	})
+	cancelled.Store(false)
+	benchCancelWorkloads(t, cancelEngine{
+		call: func(t *testing.B, fn string, arg interface{}) string {
+			var a glisp.Sexp
+			switch arg := arg.(type) {
+			case int:
+				a = glisp.NewSexpInt(arg)
+			case string:
+				a = glisp.SexpStr(arg)
+			}
+			v, err := vm.ApplyByName(fn, glisp.MakeArgs(a))
+			MustSuccess(t, err)
+			switch v := v.(type) {
+			case glisp.SexpInt:
+				return strconv.FormatInt(v.ToInt64(), 10)
+			case glisp.SexpStr:
+				return string(v)
+			}
+			t.Fatalf("%s returned %s", fn, v.SexpString())
+			return ""
+		},
+		suffix: "_cancellable",
+		arm: func() func() {
+			timer := time.AfterFunc(cancelTimeout, func() { cancelled.Store(true) })
+			return func() { timer.Stop() }
+		},
+	})
+}
+
+func benchCancel_goja(t *testing.B) {
+	const SCRIPT = `
+function spin() {
+	for (;;) {}
+}
+
+function factorial(n) {
+	if (n === 1) {
+		return 1;
+	}
+	return n * factorial(n - 1);
+}
+
+function complex_condition(n) {
+	if (n >= 0 && n <= 10) {
+		return "low";
+	} else if (n > 10 && n <= 20) {
+		return "medium";
+	} else if (n > 20 && n <= 30) {
+		return "high";
+	}
+	return "unknown";
+}
+
+const m = {
+	"key1": "value1",
+	"key2": "value2",
+	"key3": "value3",
+	"key4": "value4",
+	"key5": "value5",
+	"key6": "value6",
+	"key7": "value7",
+	"key8": "value8",
+	"key9": "value9",
+	"key10": "value10"
+};
+
+function get_from_hash(key) {
+	return m[key];
+}
+
+function parse_and_modify(json_str) {
+	let data = JSON.parse(json_str);
+	data.name = "new_name";
+	return data.name;
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	spin, ok := goja.AssertFunction(vm.Get("spin"))
+	MustTrue(t, ok)
+	funcs := make(map[string]goja.Callable)
+	for _, w := range cancelWorkloads {
+		funcs[w.fn], ok = goja.AssertFunction(vm.Get(w.fn))
+		MustTrue(t, ok)
+	}
+	subBenchmark(t, "stop", func(t *testing.B) {
+		benchStop(t, vm.ClearInterrupt, func() { vm.Interrupt("timeout") }, func() {
+			_, err := spin(goja.Undefined())
+			var interrupted *goja.InterruptedError
+			MustTrue(t, errors.As(err, &interrupted))
+		})
+	})
+	vm.ClearInterrupt()
+	benchCancelWorkloads(t, cancelEngine{
+		call: func(t *testing.B, fn string, arg interface{}) string {
+			res, err := funcs[fn](goja.Undefined(), vm.ToValue(arg))
+			MustSuccess(t, err)
+			return res.String()
+		},
+		arm: func() func() {
+			timer := time.AfterFunc(cancelTimeout, func() { vm.Interrupt("timeout") })
+			return func() { timer.Stop() }
+		},
+	})
+}
+
+func benchCancel_lua(t *testing.B) {
+	var cancelled atomic.Bool
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	l.Register("modify_json", func(l *lua.State) int {
+		s, err := modifyJSON(lua.CheckString(l, 1))
+		if err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		l.PushString(s)
+		return 1
+	})
+	// go-lua has no context support; a count hook checks the flag every
+	// 1000 instructions and raises an error to unwind the script.
+	hook := func(l *lua.State, ar lua.Debug) {
+		if cancelled.Load() {
+			lua.Errorf(l, "timeout")
+		}
+	}
+	script := `
+function spin()
+  while true do
+  end
+end
+
+function factorial(n)
+  if n == 1 then
+    return 1
+  end
+  return n * factorial(n-1)
+end
+
+function complex_condition(n)
+  if n >= 0 and n <= 10 then
+    return "low"
+  elseif n > 10 and n <= 20 then
+    return "medium"
+  elseif n > 20 and n <= 30 then
+    return "high"
+  else
+    return "unknown"
+  end
+end
+
+function parse_and_modify(json_str)
+  return modify_json(json_str)
+end
+` + luaHashScript
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	subBenchmark(t, "stop", func(t *testing.B) {
+		lua.SetDebugHook(l, hook, lua.MaskCount, 1000)
+		defer lua.SetDebugHook(l, nil, 0, 0)
+		benchStop(t, func() { cancelled.Store(false) }, func() { cancelled.Store(true) }, func() {
+			l.Global("spin")
+			err := l.ProtectedCall(0, 0, 0)
+			MustTrue(t, err != nil && strings.Contains(err.Error(), "timeout"))
+			l.Pop(1)
+		})
+	})
+	cancelled.Store(false)
+	benchCancelWorkloads(t, cancelEngine{
+		call: func(t *testing.B, fn string, arg interface{}) string {
+			l.Global(fn)
+			switch arg := arg.(type) {
+			case int:
+				l.PushInteger(arg)
+			case string:
+				l.PushString(arg)
+			}
+			err := l.ProtectedCall(1, 1, 0)
+			MustSuccess(t, err)
+			defer l.Pop(1)
+			if l.IsString(-1) && !l.IsNumber(-1) {
+				s, _ := l.ToString(-1)
+				return s
+			}
+			n, ok := l.ToInteger(-1)
+			MustTrue(t, ok)
+			return strconv.Itoa(n)
+		},
+		arm: func() func() {
+			timer := time.AfterFunc(cancelTimeout, func() { cancelled.Store(true) })
+			return func() { timer.Stop() }
+		},
+		setup: func() func() {
+			lua.SetDebugHook(l, hook, lua.MaskCount, 1000)
+			return func() { lua.SetDebugHook(l, nil, 0, 0) }
+		},
+	})
+}
+
+func benchCancel_gopherlua(t *testing.B) {
+	L := gopherlua.NewState()
+	defer L.Close()
+	L.SetGlobal("modify_json", L.NewFunction(func(L *gopherlua.LState) int {
+		s, err := modifyJSON(L.CheckString(1))
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+		L.Push(gopherlua.LString(s))
+		return 1
+	}))
+	err := L.DoString(`
+function spin()
+  while true do
+  end
+end
+
+function factorial(n)
+  if n == 1 then
+    return 1
+  end
+  return n * factorial(n-1)
+end
+
+function complex_condition(n)
+  if n >= 0 and n <= 10 then
+    return "low"
+  elseif n > 10 and n <= 20 then
+    return "medium"
+  elseif n > 20 and n <= 30 then
+    return "high"
+  else
+    return "unknown"
+  end
+end
+
+function parse_and_modify(json_str)
+  return modify_json(json_str)
+end
+` + luaHashScript)
+	MustSuccess(t, err)
+	subBenchmark(t, "stop", func(t *testing.B) {
+		var cancel context.CancelFunc
+		reset := func() {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			L.SetContext(ctx)
+		}
+		benchStop(t, reset, func() { cancel() }, func() {
+			err := L.CallByParam(gopherlua.P{Fn: L.GetGlobal("spin"), Protect: true})
+			MustTrue(t, err != nil && strings.Contains(err.Error(), context.Canceled.Error()))
+		})
+		L.RemoveContext()
+	})
+	benchCancelWorkloads(t, cancelEngine{
+		call: func(t *testing.B, fn string, arg interface{}) string {
+			var a gopherlua.LValue
+			switch arg := arg.(type) {
+			case int:
+				a = gopherlua.LNumber(arg)
+			case string:
+				a = gopherlua.LString(arg)
+			}
+			err := L.CallByParam(gopherlua.P{
+				Fn:      L.GetGlobal(fn),
+				NRet:    1,
+				Protect: true,
+			}, a)
+			MustSuccess(t, err)
+			ret := L.Get(-1)
+			L.Pop(1)
+			return ret.String()
+		},
+		arm: func() func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+			L.SetContext(ctx)
+			return cancel
+		},
+		setup: func() func() { return func() { L.RemoveContext() } },
+	})
+}
+
+func benchCancel_zygo(t *testing.B) {
+	var cancelled atomic.Bool
+	env := zygo.NewZlisp()
+	env.AddFunction("cancelled?", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		return &zygo.SexpBool{Val: cancelled.Load()}, nil
+	})
+	env.AddFunction("parseJSON", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 1 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		s, ok := args[0].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("argument of %v should be a string", name)
+		}
+		return zygo.JsonToSexp([]byte(s.S), env)
+	})
+	_, err := env.EvalString(`
+(defn spin []
+  (def n 0)
+  (for [(set n 0) (not (cancelled?)) (set n (+ n 1))] n)
+  n)
+
+(defn factorial [n] (cond (== 1 n) n (* n (factorial (- n 1)))))
+
+(defn factorial_cancellable [n]
+  (cond (cancelled?) 0
+        (== 1 n) n
+        (* n (factorial_cancellable (- n 1)))))
+
+(defn complex_condition [n]
+  (cond (and (>= n 0) (<= n 10)) "low"
+        (and (> n 10) (<= n 20)) "medium"
+        (and (> n 20) (<= n 30)) "high"
+        "unknown"))
+
+(defn complex_condition_cancellable [n]
+  (cond (cancelled?) ""
+        (and (>= n 0) (<= n 10)) "low"
+        (and (> n 10) (<= n 20)) "medium"
+        (and (> n 20) (<= n 30)) "high"
+        "unknown"))
+` + cancelHashScript + `
+(defn get_from_hash [key] (hget m key))
+
+(defn get_from_hash_cancellable [key] (cond (cancelled?) "" (hget m key)))
+
+(defn parse_and_modify [json_str]
+  (def data (parseJSON json_str))
+  (hset data "name" "new_name")
+  (hget data "name"))
+
+(defn parse_and_modify_cancellable [json_str]
+  (cond (cancelled?) ""
+        (begin
+          (def data (parseJSON json_str))
+          (hset data "name" "new_name")
+          (hget data "name"))))
+`)
+	MustSuccess(t, err)
+	find := func(name string) *zygo.SexpFunction {
+		v, _ := env.FindObject(name)
+		return v.(*zygo.SexpFunction)
+	}
+	spin := find("spin")
+	subBenchmark(t, "stop", func(t *testing.B) {
+		benchStop(t, func() { cancelled.Store(false) }, func() { cancelled.Store(true) }, func() {
+			res, err := env.Apply(spin, nil)
+			MustSuccess(t, err)
+			_, ok := res.(*zygo.SexpInt)
+			MustTrue(t, ok)
+		})
+	})
+	cancelled.Store(false)
+	funcs := make(map[string]*zygo.SexpFunction)
+	for _, w := range cancelWorkloads {
+		funcs[w.fn], funcs[w.fn+"_cancellable"] = find(w.fn), find(w.fn+"_cancellable")
+	}
+	benchCancelWorkloads(t, cancelEngine{
+		call: func(t *testing.B, fn string, arg interface{}) string {
+			var a zygo.Sexp
+			switch arg := arg.(type) {
+			case int:
+				a = &zygo.SexpInt{Val: int64(arg)}
+			case string:
+				a = &zygo.SexpStr{S: arg}
+			}
+			res, err := env.Apply(funcs[fn], []zygo.Sexp{a})
+			MustSuccess(t, err)
+			switch res := res.(type) {
+			case *zygo.SexpInt:
+				return strconv.FormatInt(res.Val, 10)
+			case *zygo.SexpStr:
+				return res.S
+			}
+			t.Fatalf("%s returned %s", fn, res.SexpString(nil))
+			return ""
+		},
+		suffix: "_cancellable",
+		arm: func() func() {
+			timer := time.AfterFunc(cancelTimeout, func() { cancelled.Store(true) })
+			return func() { timer.Stop() }
+		},
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// coldOptions configures measureCold. Every trial loads the workload into
+// a fresh engine, then makes warm calls after the first and steady more
+// calls to measure the steady state.
+type coldOptions struct {
+	trials int
+	warm   int
+	steady int
+}
+
+// coldResult is the median over all trials of one workload in one engine.
+// Setup covers creating the engine and loading the script
+// (SourceStream, RunString, DoString or EvalString).
+type coldResult struct {
+	Workload string        `json:"workload"`
+	Engine   string        `json:"engine"`
+	Trials   int           `json:"trials"`
+	Setup    time.Duration `json:"setup_ns"`
+	First    time.Duration `json:"first_call_ns"`
+	// Warm holds calls 2, 3, ... after the first.
+	Warm   []time.Duration `json:"warm_calls_ns"`
+	Steady time.Duration   `json:"steady_ns"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// coldTrial is one fresh engine: setup, first call, warm calls and the
+// mean of the steady calls.
+func coldTrial(w workload, engine string, opts coldOptions) (setup, first time.Duration, warm []time.Duration, steady time.Duration, err error) {
+	runtime.GC()
+	start := time.Now()
+	call, err := w.prepare(engine)
+	if err != nil {
+		return
+	}
+	setup = time.Since(start)
+	timed := func() (time.Duration, error) {
+		start := time.Now()
+		got, err := call()
+		d := time.Since(start)
+		if err == nil && got != w.expect {
+			err = fmt.Errorf("got %s, want %s", got, w.expect)
+		}
+		return d, err
+	}
+	if first, err = timed(); err != nil {
+		return
+	}
+	for i := 0; i < opts.warm; i++ {
+		d, err2 := timed()
+		if err = err2; err != nil {
+			return
+		}
+		warm = append(warm, d)
+	}
+	start = time.Now()
+	for i := 0; i < opts.steady; i++ {
+		if _, err = call(); err != nil {
+			return
+		}
+	}
+	steady = time.Since(start) / time.Duration(opts.steady)
+	return
+}
+
+func medianDuration(ds []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func measureCold(w workload, engine string, opts coldOptions) coldResult {
+	res := coldResult{Workload: w.name, Engine: engine, Trials: opts.trials}
+	var setups, firsts, steadies []time.Duration
+	warm := make([][]time.Duration, opts.warm)
+	for t := 0; t < opts.trials; t++ {
+		setup, first, warmCalls, steady, err := coldTrial(w, engine, opts)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		setups = append(setups, setup)
+		firsts = append(firsts, first)
+		steadies = append(steadies, steady)
+		for i, d := range warmCalls {
+			warm[i] = append(warm[i], d)
+		}
+	}
+	res.Setup = medianDuration(setups)
+	res.First = medianDuration(firsts)
+	res.Steady = medianDuration(steadies)
+	for _, ds := range warm {
+		res.Warm = append(res.Warm, medianDuration(ds))
+	}
+	return res
+}
+
+// runCold measures every testdata workload matching filter in every
+// selected engine that has a script for it.
+func runCold(filter *regexp.Regexp, engines map[string]bool, opts coldOptions, progress func(coldResult)) ([]coldResult, error) {
+	var results []coldResult
+	err := eachWorkload(filter, engines, func(w workload, engine string) {
+		res := measureCold(w, engine, opts)
+		results = append(results, res)
+		if progress != nil {
+			progress(res)
+		}
+	})
+	return results, err
+}
+
+// coldTable shows the first call and each warm call next to the steady
+// state, in ns, with the first call as a multiple of the steady state.
+func coldTable(results []coldResult, warm int) *textTable {
+	t := &textTable{header: []string{"Workload", "Engine", "setup", "call 1"}}
+	for i := 0; i < warm; i++ {
+		t.header = append(t.header, "call "+strconv.Itoa(i+2))
+	}
+	t.header = append(t.header, "steady", "call 1 / steady")
+	for _, r := range results {
+		row := []string{r.Workload, r.Engine}
+		if r.Error != "" {
+			row = append(row, "FAIL: "+r.Error)
+			for len(row) < len(t.header) {
+				row = append(row, "")
+			}
+			t.rows = append(t.rows, row)
+			continue
+		}
+		row = append(row, formatNs(float64(r.Setup)), formatNs(float64(r.First)))
+		for _, d := range r.Warm {
+			row = append(row, formatNs(float64(d)))
+		}
+		ratio := "-"
+		if r.Steady > 0 {
+			ratio = fmt.Sprintf("%.1fx", float64(r.First)/float64(r.Steady))
+		}
+		row = append(row, formatNs(float64(r.Steady)), ratio)
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
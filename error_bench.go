@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// errorPath is one way a rule can finish. Every engine defines guarded,
+// which rejects negative input and handles the failure inside the script,
+// guarded_deep, which raises the same failure 20 calls down, and validate,
+// which lets it escape to Go. For toGo paths expect is a substring of the
+// error returned to Go.
+type errorPath struct {
+	name   string
+	fn     string
+	arg    int64
+	expect string
+	toGo   bool
+}
+
+var errorPaths = []errorPath{
+	{"happy", "guarded", 1, "ok", false},
+	{"caught", "guarded", -1, "rejected: negative input", false},
+	{"caught-deep", "guarded_deep", -1, "rejected: negative input", false},
+	{"to-go-happy", "validate", 1, "ok", false},
+	{"to-go", "validate", -1, "negative input", true},
+}
+
+func benchErrorPath_glisp(t *testing.B) {
+	base := glisp.New()
+	ext.ImportCoreUtils(base)
+	// glisp has no throw/catch; failures are error values tested with error?,
+	// and assert aborts the call with a Go error.
+	err := base.SourceStream(bytes.NewBufferString(`
+(defn check [n] (cond (< n 0) (error "negative input") n))
+
+(defn descend [n depth]
+  (cond (= depth 0) (check n)
+        (let [r (descend n (- depth 1))]
+          (cond (error? r) r (+ r 1)))))
+
+(defn reject [r] (cond (error? r) (concat "rejected: " (string r)) "ok"))
+
+(defn guarded [n] (reject (check n)))
+
+(defn guarded_deep [n] (reject (descend n 20)))
+
+(defn validate [n] (assert (>= n 0) "negative input") "ok")
+`))
+	MustSuccess(t, err)
+	for _, w := range errorPaths {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			vm := base.Duplicate()
+			for i := 0; i < t.N; i++ {
+				v, err := vm.ApplyByName(w.fn, glisp.MakeArgs(glisp.NewSexpInt64(w.arg)))
+				if w.toGo {
+					MustTrue(t, err != nil && strings.Contains(err.Error(), w.expect))
+					// an aborted call leaves the vm stacks dirty, so every
+					// failure costs a fresh environment forked from base.
+					vm = base.Duplicate()
+					continue
+				}
+				MustSuccess(t, err)
+				s, ok := v.(glisp.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, string(s))
+			}
+		})
+	}
+}
+
+func benchErrorPath_goja(t *testing.B) {
+	const SCRIPT = `
+function check(n) {
+	if (n < 0) {
+		throw new Error("negative input");
+	}
+	return n;
+}
+
+function descend(n, depth) {
+	if (depth === 0) {
+		return check(n);
+	}
+	return descend(n, depth - 1) + 1;
+}
+
+function guarded(n) {
+	try {
+		check(n);
+		return "ok";
+	} catch (e) {
+		return "rejected: " + e.message;
+	}
+}
+
+function guarded_deep(n) {
+	try {
+		descend(n, 20);
+		return "ok";
+	} catch (e) {
+		return "rejected: " + e.message;
+	}
+}
+
+function validate(n) {
+	check(n);
+	return "ok";
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	for _, w := range errorPaths {
+		f, ok := goja.AssertFunction(vm.Get(w.fn))
+		MustTrue(t, ok)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := f(goja.Undefined(), vm.ToValue(w.arg))
+				if w.toGo {
+					var ex *goja.Exception
+					MustTrue(t, errors.As(err, &ex) && strings.Contains(ex.Error(), w.expect))
+					continue
+				}
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.String())
+			}
+		})
+	}
+}
+
+func benchErrorPath_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	script := `
+local function check(n)
+  if n < 0 then
+    error("negative input", 0)
+  end
+  return n
+end
+
+local function descend(n, depth)
+  if depth == 0 then
+    return check(n)
+  end
+  return descend(n, depth - 1) + 1
+end
+
+local function reject(ok, err)
+  if ok then
+    return "ok"
+  end
+  return "rejected: " .. err
+end
+
+function guarded(n)
+  return reject(pcall(check, n))
+end
+
+function guarded_deep(n)
+  return reject(pcall(descend, n, 20))
+end
+
+function validate(n)
+  check(n)
+  return "ok"
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	for _, w := range errorPaths {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				l.Global(w.fn)
+				l.PushInteger(int(w.arg))
+				err = l.ProtectedCall(1, 1, 0)
+				if w.toGo {
+					MustTrue(t, err != nil && strings.Contains(err.Error(), w.expect))
+					// the error value is left on the stack in place of the result.
+					l.Pop(1)
+					continue
+				}
+				MustSuccess(t, err)
+				s, ok := l.ToString(-1)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s)
+				l.Pop(1)
+			}
+		})
+	}
+}
+
+func benchErrorPath_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	// zygo can neither build nor catch errors in script, so raise comes from
+	// Go and in-script failures use the convention of returning a message string.
+	env.AddFunction("raise", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 1 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		s, ok := args[0].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("%s: argument must be a string", name)
+		}
+		return zygo.SexpNull, errors.New(s.S)
+	})
+	_, err := env.EvalString(`
+(defn check [n] (cond (< n 0) "negative input" n))
+
+(defn descend [n depth]
+  (cond (== depth 0) (check n)
+        (begin
+          (def r (descend n (- depth 1)))
+          (cond (string? r) r (+ r 1)))))
+
+(defn reject [r] (cond (string? r) (concat "rejected: " r) "ok"))
+
+(defn guarded [n] (reject (check n)))
+
+(defn guarded_deep [n] (reject (descend n 20)))
+
+(defn validate [n]
+  (cond (< n 0) (raise "negative input") "ok"))
+`)
+	MustSuccess(t, err)
+	for _, w := range errorPaths {
+		v, _ := env.FindObject(w.fn)
+		fn := v.(*zygo.SexpFunction)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := env.Apply(fn, []zygo.Sexp{&zygo.SexpInt{Val: w.arg}})
+				if w.toGo {
+					MustTrue(t, err != nil && strings.Contains(err.Error(), w.expect))
+					// a failed Apply leaves its frames on the stacks; without
+					// Clear every later call gets slower.
+					env.Clear()
+					continue
+				}
+				MustSuccess(t, err)
+				s, ok := res.(*zygo.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s.S)
+			}
+		})
+	}
+}
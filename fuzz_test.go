@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// The fuzz targets feed random inputs to the shared workloads and require
+// glisp to give the same answer as goja and Lua:
+//
+//	go test -run xxx -fuzz FuzzComplexCondition -fuzztime 1m
+//
+// Only disagreements where goja and Lua agree with each other are reported,
+// so a failure always points at glisp. Go saves the failing input under
+// testdata/fuzz/<target>, and plain go test replays it from then on.
+
+// fuzzAnswer is what one engine made of an input. Error messages differ
+// between engines, so only the fact that the call failed is compared.
+type fuzzAnswer struct {
+	val    string
+	failed bool
+}
+
+func answer(val string, err error) fuzzAnswer {
+	if err != nil {
+		return fuzzAnswer{failed: true}
+	}
+	return fuzzAnswer{val: val}
+}
+
+func mustAgree(t *testing.T, glispAns, gojaAns, luaAns fuzzAnswer) {
+	if gojaAns != luaAns {
+		t.Skipf("goja %+v and lua %+v disagree, no reference answer", gojaAns, luaAns)
+	}
+	if glispAns != gojaAns {
+		t.Errorf("glisp answered %+v, goja and lua answered %+v", glispAns, gojaAns)
+	}
+}
+
+// glispCaller sources script once and returns a function calling fn on a
+// fresh duplicate of that environment, because a failed call leaves the
+// environment unusable.
+func glispCaller(t *testing.F, script string, imports ...func(*glisp.Environment) error) func(fn string, args ...glisp.Sexp) fuzzAnswer {
+	base := glisp.New()
+	for _, imp := range imports {
+		if err := imp(base); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := base.SourceStream(bytes.NewBufferString(script)); err != nil {
+		t.Fatal(err)
+	}
+	return func(fn string, args ...glisp.Sexp) fuzzAnswer {
+		v, err := base.Duplicate().ApplyByName(fn, glisp.MakeArgs(args...))
+		if err != nil {
+			return answer("", err)
+		}
+		s, ok := v.(glisp.SexpStr)
+		if !ok {
+			return fuzzAnswer{val: v.SexpString()}
+		}
+		return fuzzAnswer{val: string(s)}
+	}
+}
+
+func gojaCaller(t *testing.F, script string) func(fn string, args ...interface{}) fuzzAnswer {
+	vm := goja.New()
+	if _, err := vm.RunString(script); err != nil {
+		t.Fatal(err)
+	}
+	return func(fn string, args ...interface{}) fuzzAnswer {
+		f, ok := goja.AssertFunction(vm.Get(fn))
+		if !ok {
+			t.Fatalf("%s is not a function", fn)
+		}
+		vals := make([]goja.Value, len(args))
+		for i, arg := range args {
+			vals[i] = vm.ToValue(arg)
+		}
+		res, err := f(goja.Undefined(), vals...)
+		if err != nil {
+			return answer("", err)
+		}
+		return fuzzAnswer{val: res.String()}
+	}
+}
+
+func luaCaller(t *testing.F, l *lua.State, script string) func(fn string, push func()) fuzzAnswer {
+	lua.OpenLibraries(l)
+	if err := lua.DoString(l, script); err != nil {
+		t.Fatal(err)
+	}
+	return func(fn string, push func()) fuzzAnswer {
+		top := l.Top()
+		l.Global(fn)
+		push()
+		err := l.ProtectedCall(l.Top()-top-1, 1, 0)
+		defer l.SetTop(top)
+		if err != nil {
+			return answer("", err)
+		}
+		s, _ := l.ToString(-1)
+		return fuzzAnswer{val: s}
+	}
+}
+
+func FuzzComplexCondition(f *testing.F) {
+	for _, n := range []int64{-1, 0, 10, 11, 15, 20, 21, 30, 31} {
+		f.Add(n)
+	}
+	callGlisp := glispCaller(f, `
+(defn complex-condition [n]
+  (cond
+    (<= 0 n 10) "low"
+    (<= 10 n 20) "medium"
+    (<= 20 n 30) "high"
+    "unknown"))
+`, ext.ImportCoreUtils)
+	callGoja := gojaCaller(f, `
+function complex_condition(n) {
+  if (n >= 0 && n <= 10) {
+    return "low";
+  } else if (n > 10 && n <= 20) {
+    return "medium";
+  } else if (n > 20 && n <= 30) {
+    return "high";
+  } else {
+    return "unknown";
+  }
+}
+`)
+	l := lua.NewState()
+	callLua := luaCaller(f, l, `
+function complex_condition(n)
+  if n >= 0 and n <= 10 then
+    return "low"
+  elseif n > 10 and n <= 20 then
+    return "medium"
+  elseif n > 20 and n <= 30 then
+    return "high"
+  else
+    return "unknown"
+  end
+end
+`)
+	f.Fuzz(func(t *testing.T, n int64) {
+		mustAgree(t,
+			callGlisp("complex-condition", glisp.NewSexpInt64(n)),
+			callGoja("complex_condition", n),
+			callLua("complex_condition", func() { l.PushInteger(int(n)) }))
+	})
+}
+
+func FuzzHashAccess(f *testing.F) {
+	for _, key := range []string{"key1", "key5", "key10", "key11", "", "KEY1", "key1 "} {
+		f.Add(key)
+	}
+	callGlisp := glispCaller(f, `
+(def m (hash
+    "key1" "value1"
+    "key2" "value2"
+    "key3" "value3"
+    "key4" "value4"
+    "key5" "value5"
+    "key6" "value6"
+    "key7" "value7"
+    "key8" "value8"
+    "key9" "value9"
+    "key10" "value10"))
+(defn get-from-hash [key] (hget m key "missing"))
+`, ext.ImportCoreUtils)
+	// a plain object lookup would also find inherited keys such as
+	// "constructor", which is a JS quirk rather than a glisp bug.
+	callGoja := gojaCaller(f, `
+const m = new Map([
+    ["key1", "value1"],
+    ["key2", "value2"],
+    ["key3", "value3"],
+    ["key4", "value4"],
+    ["key5", "value5"],
+    ["key6", "value6"],
+    ["key7", "value7"],
+    ["key8", "value8"],
+    ["key9", "value9"],
+    ["key10", "value10"]
+]);
+
+function get_from_hash(key) {
+    return m.has(key) ? m.get(key) : "missing";
+}
+`)
+	l := lua.NewState()
+	callLua := luaCaller(f, l, `
+local m = {
+    ["key1"] = "value1",
+    ["key2"] = "value2",
+    ["key3"] = "value3",
+    ["key4"] = "value4",
+    ["key5"] = "value5",
+    ["key6"] = "value6",
+    ["key7"] = "value7",
+    ["key8"] = "value8",
+    ["key9"] = "value9",
+    ["key10"] = "value10"
+}
+
+function get_from_hash(key)
+    return m[key] or "missing"
+end
+`)
+	f.Fuzz(func(t *testing.T, key string) {
+		if !utf8.ValidString(key) {
+			t.Skip("goja cannot hold invalid UTF-8 strings")
+		}
+		mustAgree(t,
+			callGlisp("get-from-hash", glisp.SexpStr(key)),
+			callGoja("get_from_hash", key),
+			callLua("get_from_hash", func() { l.PushString(key) }))
+	})
+}
+
+func FuzzJSONParseAndModify(f *testing.F) {
+	for _, doc := range []string{
+		`{"name": "John", "age": 30, "city": "New York"}`,
+		`{"name": "John", "city": 7}`,
+		`{"city": "Paris", "city": "Rome"}`,
+		`{"city": "é\n\"x\""}`,
+		`{}`,
+	} {
+		f.Add(doc)
+	}
+	// parse_and_modify renames the record and reports both the new name and
+	// the city, or "" when city is missing or not a string.
+	callGlisp := glispCaller(f, `
+(defn parse_and_modify [json_str]
+    (def data (json/parse json_str))
+    (hset! data "name" "new_name")
+    (def city (hget data "city" ""))
+    (concat (hget data "name") "|" (cond (string? city) city "")))
+`, ext.ImportCoreUtils, ext.ImportJSON)
+	callGoja := gojaCaller(f, `
+function parse_and_modify(json_str) {
+    let data = JSON.parse(json_str);
+    data.name = "new_name";
+    return data.name + "|" + (typeof data.city === "string" ? data.city : "");
+}
+`)
+	// go-lua has no JSON decoder, so json_decode hands the script the
+	// document as a table, as in RuleEngine, and the script does the rest.
+	l := lua.NewState()
+	l.Register("json_decode", func(l *lua.State) int {
+		var data interface{}
+		if err := json.Unmarshal([]byte(lua.CheckString(l, 1)), &data); err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		pushLuaJSON(l, data)
+		return 1
+	})
+	callLua := luaCaller(f, l, `
+function parse_and_modify(json_str)
+    local data = json_decode(json_str)
+    data.name = "new_name"
+    local city = data.city
+    if type(city) ~= "string" then
+        city = ""
+    end
+    return data.name .. "|" .. city
+end
+`)
+	f.Fuzz(func(t *testing.T, doc string) {
+		var data map[string]interface{}
+		if !utf8.ValidString(doc) || json.Unmarshal([]byte(doc), &data) != nil || data == nil {
+			t.Skip("only JSON objects are compared; engines disagree on what else to reject")
+		}
+		mustAgree(t,
+			callGlisp("parse_and_modify", glisp.SexpStr(doc)),
+			callGoja("parse_and_modify", doc),
+			callLua("parse_and_modify", func() { l.PushString(doc) }))
+	})
+}
+
+func FuzzRegexpMatch(f *testing.F) {
+	for _, s := range []string{"15744882345", "1574488234", "157448823456", "15744882345\n", "１5744882345", ""} {
+		f.Add(s)
+	}
+	callGlisp := glispCaller(f, `(defn testPhoneNumber [n] (regexp/match "^\\d{3}\\d{4}\\d{4}$" n))`, ext.ImportRegex)
+	// native RegExp rather than the Go helper of the benchmark, so the
+	// answer does not come from the same regexp package glisp uses.
+	callGoja := gojaCaller(f, `
+function testPhoneNumber(phone) {
+	return /^\d{3}\d{4}\d{4}$/.test(phone);
+}
+`)
+	// go-lua has no pattern matching, so the Lua side checks for eleven
+	// ASCII digits byte by byte, which is what the pattern means.
+	l := lua.NewState()
+	callLua := luaCaller(f, l, `
+function testPhoneNumber(n)
+  if #n ~= 11 then
+    return "false"
+  end
+  for i = 1, #n do
+    local c = string.byte(n, i)
+    if c < 48 or c > 57 then
+      return "false"
+    end
+  end
+  return "true"
+end
+`)
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip("goja cannot hold invalid UTF-8 strings")
+		}
+		mustAgree(t,
+			callGlisp("testPhoneNumber", glisp.SexpStr(s)),
+			callGoja("testPhoneNumber", s),
+			callLua("testPhoneNumber", func() { l.PushString(s) }))
+	})
+}
@@ -0,0 +1,116 @@
+//go:build ignore
+
+// gen_suite collects every bench<Workload>_<engine>(t *testing.B) function
+// of the package into suite_gen.go, and writes the matching
+// Benchmark<Workload>_<engine> wrappers go test discovers into
+// suite_gen_test.go. Run it with go generate after adding a benchmark.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const header = "// Code generated by gen_suite.go; DO NOT EDIT.\n\n"
+
+// headline lists the workloads that lead the suite, and so every table of
+// a run, whichever file declares them.
+var headline = []string{"RuleEngine"}
+
+func main() {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(files)
+	var names []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || strings.HasPrefix(file, "suite_gen") || file == "gen_suite.go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if ok && isBenchmark(fn) {
+				names = append(names, strings.TrimPrefix(fn.Name.Name, "bench"))
+			}
+		}
+	}
+
+	// Group the engines of a workload together, headline workloads first
+	// and the others in the order they are first declared.
+	first := make(map[string]int)
+	for i, w := range headline {
+		first[w] = i - len(headline)
+	}
+	for i, name := range names {
+		if _, ok := first[workloadOf(name)]; !ok {
+			first[workloadOf(name)] = i
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return first[workloadOf(names[i])] < first[workloadOf(names[j])]
+	})
+
+	var suite, wrappers bytes.Buffer
+	suite.WriteString(header + "package main\n\nvar suite = []suiteBenchmark{\n")
+	wrappers.WriteString(header + "package main\n\nimport \"testing\"\n")
+	for _, name := range names {
+		w := workloadOf(name)
+		fmt.Fprintf(&suite, "\t{%q, %q, bench%s},\n", w, name[len(w)+1:], name)
+		fmt.Fprintf(&wrappers, "\nfunc Benchmark%s(t *testing.B) { bench%s(t) }\n", name, name)
+	}
+	suite.WriteString("}\n")
+	write("suite_gen.go", suite.Bytes())
+	write("suite_gen_test.go", wrappers.Bytes())
+}
+
+func workloadOf(name string) string {
+	return name[:strings.LastIndex(name, "_")]
+}
+
+// isBenchmark reports whether fn is a top-level func bench<Workload>_<engine>
+// taking a single *testing.B.
+func isBenchmark(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	if fn.Recv != nil || !strings.HasPrefix(name, "bench") || !strings.Contains(name, "_") {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 || fn.Type.Results != nil {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "testing" && sel.Sel.Name == "B"
+}
+
+func write(file string, src []byte) {
+	src, err := format.Source(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(file, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
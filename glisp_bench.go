@@ -8,7 +8,7 @@ import (
 	ext "github.com/qjpcpu/glisp/extensions"
 )
 
-func BenchmarkFactorial_glisp(t *testing.B) {
+func benchFactorial_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	vm.SourceStream(bytes.NewBufferString(`(defn factorial[n]
@@ -23,7 +23,7 @@ func BenchmarkFactorial_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkRegexpMatch_glisp(t *testing.B) {
+func benchRegexpMatch_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportRegex(vm)
 	err := vm.SourceStream(bytes.NewBufferString(`(defn testPhoneNumber[n]
@@ -39,7 +39,7 @@ func BenchmarkRegexpMatch_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkComplexCondition_glisp(t *testing.B) {
+func benchComplexCondition_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	vm.SourceStream(bytes.NewBufferString(`
@@ -59,7 +59,7 @@ func BenchmarkComplexCondition_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkFormatTime_glisp(t *testing.B) {
+func benchFormatTime_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportTime(vm)
 	err := vm.SourceStream(bytes.NewBufferString(`(defn formatTime [t]
@@ -75,7 +75,7 @@ func BenchmarkFormatTime_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkHashWrite_glisp(t *testing.B) {
+func benchHashWrite_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	err := vm.SourceStream(bytes.NewBufferString(`
@@ -100,7 +100,7 @@ func BenchmarkHashWrite_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkHashDelete_glisp(t *testing.B) {
+func benchHashDelete_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	err := vm.SourceStream(bytes.NewBufferString(`
@@ -124,7 +124,7 @@ func BenchmarkHashDelete_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkHashAccess_glisp(t *testing.B) {
+func benchHashAccess_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	err := vm.SourceStream(bytes.NewBufferString(`
@@ -149,7 +149,7 @@ func BenchmarkHashAccess_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkJSONParseAndModify_glisp(t *testing.B) {
+func benchJSONParseAndModify_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportJSON(vm)
 	vm.SourceStream(bytes.NewBufferString(`
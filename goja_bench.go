@@ -9,7 +9,7 @@ import (
 	"github.com/dop251/goja"
 )
 
-func BenchmarkFactorial_goja(t *testing.B) {
+func benchFactorial_goja(t *testing.B) {
 	const SCRIPT = `
 function factorial(n) {
     return n === 1 ? n : n * factorial(--n);
@@ -28,7 +28,7 @@ function factorial(n) {
 	}
 }
 
-func BenchmarkRegexpMatch_goja(t *testing.B) {
+func benchRegexpMatch_goja(t *testing.B) {
 	const SCRIPT = `
 		function testPhoneNumber(phone) {
 			return test(phone, "^\\d{3}\\d{4}\\d{4}$");
@@ -65,7 +65,7 @@ func BenchmarkRegexpMatch_goja(t *testing.B) {
 	}
 }
 
-func BenchmarkComplexCondition_goja(t *testing.B) {
+func benchComplexCondition_goja(t *testing.B) {
 	const SCRIPT = `
 function complex_condition(n) {
   if (n >= 0 && n <= 10) {
@@ -91,7 +91,7 @@ function complex_condition(n) {
 	}
 }
 
-func BenchmarkFormatTime_goja(t *testing.B) {
+func benchFormatTime_goja(t *testing.B) {
 	const SCRIPT = `
 function formatTime(t) {
 	return format(t, "2006-01-02T15:04:05Z", "2006年01月02日 15时04分05秒");
@@ -119,7 +119,7 @@ function formatTime(t) {
 	}
 }
 
-func BenchmarkHashAccess_goja(t *testing.B) {
+func benchHashAccess_goja(t *testing.B) {
 	const SCRIPT = `
 const m = {
     "key1": "value1",
@@ -150,7 +150,7 @@ function get_from_hash(key) {
 	}
 }
 
-func BenchmarkHashWrite_goja(t *testing.B) {
+func benchHashWrite_goja(t *testing.B) {
 	const SCRIPT = `
 const m = {
     "key1": "value1",
@@ -180,7 +180,7 @@ function set_in_hash(key, value) {
 	}
 }
 
-func BenchmarkHashDelete_goja(t *testing.B) {
+func benchHashDelete_goja(t *testing.B) {
 	const SCRIPT = `
 const m = {
     "key1": "value1",
@@ -210,7 +210,7 @@ function delete_from_hash(key) {
 	}
 }
 
-func BenchmarkJSONParseAndModify_goja(t *testing.B) {
+func benchJSONParseAndModify_goja(t *testing.B) {
 	const SCRIPT = `
 function parse_and_modify(json_str) {
     let data = JSON.parse(json_str);
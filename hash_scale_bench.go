@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+var hashSizes = []int{100, 1000, 10000}
+
+// hashOp is one whole-table operation. Every engine's script keeps the keys
+// handed to setup and a table m filled with key i -> i, and defines fn
+// returning expect. Ops marked refill consume m, so it is rebuilt off the clock.
+type hashOp struct {
+	name   string
+	fn     string
+	expect int64
+	refill bool
+}
+
+func hashOps(n int) []hashOp {
+	return []hashOp{
+		{"insert", "insert_all", int64(n - 1), false},
+		{"iterate", "iterate_all", int64(n * (n - 1) / 2), false},
+		{"hit", "lookup_hits", int64(n), false},
+		{"miss", "lookup_misses", int64(n), false},
+		{"delete", "delete_all", -1, true},
+	}
+}
+
+// hashKeys returns n distinct keys of the given kind and n keys of the same
+// kind that are never inserted. Symbol keys are returned as their names.
+func hashKeys(kind string, n int) (hits, misses []interface{}) {
+	hits = make([]interface{}, n)
+	misses = make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		switch kind {
+		case "int":
+			hits[i], misses[i] = int64(i*7), int64(-i-1)
+		default:
+			hits[i], misses[i] = fmt.Sprintf("key%d", i), fmt.Sprintf("miss%d", i)
+		}
+	}
+	return
+}
+
+func benchHashScale_glisp(t *testing.B) {
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	err := vm.SourceStream(bytes.NewBufferString(`
+(def keys [])
+(def misses [])
+(def m (hash))
+
+(defn fill-from [h i n]
+  (cond (= i n) h
+        (begin (hset! h (aget keys i) i) (fill-from h (+ i 1) n))))
+
+(defn fill [h] (fill-from h 0 (len keys)))
+
+(defn setup [ks ms]
+  (set! keys ks)
+  (set! misses ms)
+  (set! m (fill (hash))))
+
+(defn refill [] (set! m (fill (hash))))
+
+(defn insert_all [] (hget (fill (hash)) (aget keys (- (len keys) 1))))
+
+(defn iterate_all [] (foldl (fn [kv acc] (+ (cdr kv) acc)) 0 m))
+
+(defn count-found [ks i n acc]
+  (cond (= i n) acc
+        (count-found ks (+ i 1) n (cond (>= (hget m (aget ks i) -1) 0) (+ acc 1) acc))))
+
+(defn lookup_hits [] (count-found keys 0 (len keys) 0))
+
+(defn lookup_misses [] (- (len misses) (count-found misses 0 (len misses) 0)))
+
+(defn drain [i n]
+  (cond (= i n) (hget m (aget keys (- n 1)) -1)
+        (begin (hdel! m (aget keys i)) (drain (+ i 1) n))))
+
+(defn delete_all [] (drain 0 (len keys)))
+`))
+	MustSuccess(t, err)
+	toArray := func(keys []interface{}, kind string) glisp.SexpArray {
+		arr := make(glisp.SexpArray, len(keys))
+		for i, k := range keys {
+			switch k := k.(type) {
+			case int64:
+				arr[i] = glisp.NewSexpInt64(k)
+			case string:
+				if kind == "symbol" {
+					arr[i] = vm.MakeSymbol(k)
+				} else {
+					arr[i] = glisp.SexpStr(k)
+				}
+			}
+		}
+		return arr
+	}
+	for _, kind := range []string{"string", "int", "symbol"} {
+		for _, n := range hashSizes {
+			hits, misses := hashKeys(kind, n)
+			_, err := vm.ApplyByName("setup", glisp.MakeArgs(toArray(hits, kind), toArray(misses, kind)))
+			MustSuccess(t, err)
+			for _, op := range hashOps(n) {
+				subBenchmark(t, fmt.Sprintf("%s/n=%d/%s", kind, n, op.name), func(t *testing.B) {
+					for i := 0; i < t.N; i++ {
+						if op.refill {
+							t.StopTimer()
+							_, err := vm.ApplyByName("refill", glisp.MakeArgs())
+							MustSuccess(t, err)
+							t.StartTimer()
+						}
+						v, err := vm.ApplyByName(op.fn, glisp.MakeArgs())
+						MustSuccess(t, err)
+						num, ok := v.(glisp.SexpInt)
+						MustTrue(t, ok)
+						MustEqualInt64(t, op.expect, num.ToInt64())
+					}
+				})
+			}
+		}
+	}
+}
+
+func benchHashScale_goja(t *testing.B) {
+	// Plain objects coerce every key to a string property; Map keeps key types.
+	scripts := []struct {
+		name   string
+		script string
+	}{
+		{"object", `
+let keys = [], misses = [], m = {};
+
+function fill(h) {
+	for (let i = 0; i < keys.length; i++) {
+		h[keys[i]] = i;
+	}
+	return h;
+}
+
+function setup(ks, ms) {
+	keys = ks;
+	misses = ms;
+	m = fill({});
+}
+
+function refill() {
+	m = fill({});
+}
+
+function insert_all() {
+	return fill({})[keys[keys.length - 1]];
+}
+
+function iterate_all() {
+	let sum = 0;
+	for (const k in m) {
+		sum += m[k];
+	}
+	return sum;
+}
+
+function lookup_hits() {
+	let c = 0;
+	for (let i = 0; i < keys.length; i++) {
+		if (m[keys[i]] !== undefined) c++;
+	}
+	return c;
+}
+
+function lookup_misses() {
+	let c = 0;
+	for (let i = 0; i < misses.length; i++) {
+		if (m[misses[i]] === undefined) c++;
+	}
+	return c;
+}
+
+function delete_all() {
+	for (let i = 0; i < keys.length; i++) {
+		delete m[keys[i]];
+	}
+	const v = m[keys[keys.length - 1]];
+	return v === undefined ? -1 : v;
+}
+`},
+		{"map", `
+let keys = [], misses = [], m = new Map();
+
+function fill(h) {
+	for (let i = 0; i < keys.length; i++) {
+		h.set(keys[i], i);
+	}
+	return h;
+}
+
+function setup(ks, ms) {
+	keys = ks;
+	misses = ms;
+	m = fill(new Map());
+}
+
+function refill() {
+	m = fill(new Map());
+}
+
+function insert_all() {
+	return fill(new Map()).get(keys[keys.length - 1]);
+}
+
+function iterate_all() {
+	let sum = 0;
+	for (const v of m.values()) {
+		sum += v;
+	}
+	return sum;
+}
+
+function lookup_hits() {
+	let c = 0;
+	for (let i = 0; i < keys.length; i++) {
+		if (m.get(keys[i]) !== undefined) c++;
+	}
+	return c;
+}
+
+function lookup_misses() {
+	let c = 0;
+	for (let i = 0; i < misses.length; i++) {
+		if (m.get(misses[i]) === undefined) c++;
+	}
+	return c;
+}
+
+function delete_all() {
+	for (let i = 0; i < keys.length; i++) {
+		m.delete(keys[i]);
+	}
+	const v = m.get(keys[keys.length - 1]);
+	return v === undefined ? -1 : v;
+}
+`},
+	}
+	for _, s := range scripts {
+		vm := goja.New()
+		_, err := vm.RunString(s.script)
+		MustSuccess(t, err)
+		setup, ok := goja.AssertFunction(vm.Get("setup"))
+		MustTrue(t, ok)
+		refill, ok := goja.AssertFunction(vm.Get("refill"))
+		MustTrue(t, ok)
+		for _, kind := range []string{"string", "int"} {
+			for _, n := range hashSizes {
+				hits, misses := hashKeys(kind, n)
+				_, err := setup(goja.Undefined(), vm.NewArray(hits...), vm.NewArray(misses...))
+				MustSuccess(t, err)
+				for _, op := range hashOps(n) {
+					f, ok := goja.AssertFunction(vm.Get(op.fn))
+					MustTrue(t, ok)
+					subBenchmark(t, fmt.Sprintf("%s/%s/n=%d/%s", s.name, kind, n, op.name), func(t *testing.B) {
+						for i := 0; i < t.N; i++ {
+							if op.refill {
+								t.StopTimer()
+								_, err := refill(goja.Undefined())
+								MustSuccess(t, err)
+								t.StartTimer()
+							}
+							res, err := f(goja.Undefined())
+							MustSuccess(t, err)
+							MustEqualInt64(t, op.expect, res.ToInteger())
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func benchHashScale_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	script := `
+local keys, misses, m = {}, {}, {}
+
+local function fill(h)
+  for i = 1, #keys do
+    h[keys[i]] = i - 1
+  end
+  return h
+end
+
+function setup(ks, ms)
+  keys = ks
+  misses = ms
+  m = fill({})
+end
+
+function refill()
+  m = fill({})
+end
+
+function insert_all()
+  return fill({})[keys[#keys]]
+end
+
+function iterate_all()
+  local sum = 0
+  for _, v in pairs(m) do
+    sum = sum + v
+  end
+  return sum
+end
+
+function lookup_hits()
+  local c = 0
+  for i = 1, #keys do
+    if m[keys[i]] ~= nil then
+      c = c + 1
+    end
+  end
+  return c
+end
+
+function lookup_misses()
+  local c = 0
+  for i = 1, #misses do
+    if m[misses[i]] == nil then
+      c = c + 1
+    end
+  end
+  return c
+end
+
+function delete_all()
+  for i = 1, #keys do
+    m[keys[i]] = nil
+  end
+  local v = m[keys[#keys]]
+  if v == nil then
+    return -1
+  end
+  return v
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	pushKeys := func(keys []interface{}) {
+		l.CreateTable(len(keys), 0)
+		for i, k := range keys {
+			switch k := k.(type) {
+			case int64:
+				l.PushInteger(int(k))
+			case string:
+				l.PushString(k)
+			}
+			l.RawSetInt(-2, i+1)
+		}
+	}
+	for _, kind := range []string{"string", "int"} {
+		for _, n := range hashSizes {
+			hits, misses := hashKeys(kind, n)
+			l.Global("setup")
+			pushKeys(hits)
+			pushKeys(misses)
+			err = l.ProtectedCall(2, 0, 0)
+			MustSuccess(t, err)
+			for _, op := range hashOps(n) {
+				subBenchmark(t, fmt.Sprintf("%s/n=%d/%s", kind, n, op.name), func(t *testing.B) {
+					for i := 0; i < t.N; i++ {
+						if op.refill {
+							t.StopTimer()
+							l.Global("refill")
+							err = l.ProtectedCall(0, 0, 0)
+							MustSuccess(t, err)
+							t.StartTimer()
+						}
+						l.Global(op.fn)
+						err = l.ProtectedCall(0, 1, 0)
+						MustSuccess(t, err)
+						v, _ := l.ToInteger(-1)
+						MustEqualInt64(t, op.expect, int64(v))
+						l.Pop(1)
+					}
+				})
+			}
+		}
+	}
+}
+
+func benchHashScale_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	_, err := env.EvalString(`
+(def hitKeys (array))
+(def missKeys (array))
+(def m (hash))
+
+(defn fill [h]
+  (for [(def i 0) (< i (len hitKeys)) (set i (+ i 1))]
+    (hset h (aget hitKeys i) i))
+  h)
+
+(defn setup [ks ms]
+  (set hitKeys ks)
+  (set missKeys ms)
+  (set m (fill (hash))))
+
+(defn refill [] (set m (fill (hash))))
+
+(defn insert_all [] (hget (fill (hash)) (aget hitKeys (- (len hitKeys) 1))))
+
+(defn iterate_all []
+  (def sum 0)
+  (for [(def i 0) (< i (len m)) (set i (+ i 1))]
+    (set sum (+ sum (second (hpair m i)))))
+  sum)
+
+(defn lookup_hits []
+  (def c 0)
+  (for [(def i 0) (< i (len hitKeys)) (set i (+ i 1))]
+    (cond (>= (hget m (aget hitKeys i) -1) 0) (set c (+ c 1)) 0))
+  c)
+
+(defn lookup_misses []
+  (def c 0)
+  (for [(def i 0) (< i (len missKeys)) (set i (+ i 1))]
+    (cond (< (hget m (aget missKeys i) -1) 0) (set c (+ c 1)) 0))
+  c)
+
+(defn delete_all []
+  (for [(def i 0) (< i (len hitKeys)) (set i (+ i 1))]
+    (hdel m (aget hitKeys i)))
+  (hget m (aget hitKeys (- (len hitKeys) 1)) -1))
+`)
+	MustSuccess(t, err)
+	toArray := func(hitKeys []interface{}, kind string) *zygo.SexpArray {
+		arr := make([]zygo.Sexp, len(hitKeys))
+		for i, k := range hitKeys {
+			switch k := k.(type) {
+			case int64:
+				arr[i] = &zygo.SexpInt{Val: k}
+			case string:
+				if kind == "symbol" {
+					arr[i] = env.MakeSymbol(k)
+				} else {
+					arr[i] = &zygo.SexpStr{S: k}
+				}
+			}
+		}
+		return &zygo.SexpArray{Val: arr, Env: env}
+	}
+	find := func(name string) *zygo.SexpFunction {
+		v, _ := env.FindObject(name)
+		return v.(*zygo.SexpFunction)
+	}
+	setup, refill := find("setup"), find("refill")
+	for _, kind := range []string{"string", "int", "symbol"} {
+		for _, n := range hashSizes {
+			hits, misses := hashKeys(kind, n)
+			_, err := env.Apply(setup, []zygo.Sexp{toArray(hits, kind), toArray(misses, kind)})
+			MustSuccess(t, err)
+			for _, op := range hashOps(n) {
+				fn := find(op.fn)
+				subBenchmark(t, fmt.Sprintf("%s/n=%d/%s", kind, n, op.name), func(t *testing.B) {
+					for i := 0; i < t.N; i++ {
+						if op.refill {
+							t.StopTimer()
+							_, err := env.Apply(refill, []zygo.Sexp{})
+							MustSuccess(t, err)
+							t.StartTimer()
+						}
+						res, err := env.Apply(fn, []zygo.Sexp{})
+						MustSuccess(t, err)
+						num, ok := res.(*zygo.SexpInt)
+						MustTrue(t, ok)
+						MustEqualInt64(t, op.expect, num.Val)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// histogramSubBits sets the precision of latencyHistogram: values below
+// 1<<histogramSubBits are exact and larger ones are kept to within 1 part in
+// 1<<(histogramSubBits-1), about 1.6%.
+const histogramSubBits = 7
+
+// latencyHistogram is a log-linear histogram in the style of HdrHistogram:
+// every power of two is split into the same number of linear buckets, so
+// recording is constant time and the relative error is bounded whatever
+// the range, from sub-microsecond calls to GC pauses.
+type latencyHistogram struct {
+	counts []int64
+	total  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func histogramIndex(v int64) int {
+	const sub = 1 << histogramSubBits
+	if v < sub {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histogramSubBits
+	return sub + (shift-1)*(sub/2) + int(v>>shift) - sub/2
+}
+
+// histogramBucket returns the smallest and largest value recorded into
+// bucket i.
+func histogramBucket(i int) (lo, hi int64) {
+	const sub = 1 << histogramSubBits
+	if i < sub {
+		return int64(i), int64(i)
+	}
+	shift := (i-sub)/(sub/2) + 1
+	top := int64((i-sub)%(sub/2) + sub/2)
+	lo = top << shift
+	return lo, lo + 1<<shift - 1
+}
+
+func (h *latencyHistogram) record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	i := histogramIndex(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.total++
+	h.sum += v
+}
+
+// percentile returns the value below which p percent of the recorded
+// values fall, as the largest value of the bucket it ends in, like
+// HdrHistogram. percentile(100) is the exact maximum.
+func (h *latencyHistogram) percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			_, hi := histogramBucket(i)
+			if hi > h.max {
+				return h.max
+			}
+			return hi
+		}
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.total)
+}
+
+// histogramBin is a count of values up to UpTo, above the previous bin.
+type histogramBin struct {
+	UpTo  int64 `json:"up_to_ns"`
+	Count int64 `json:"count"`
+}
+
+// bins folds the histogram into binsPerDecade logarithmic bins per power
+// of ten, leaving out empty ones, which is all a chart needs.
+func (h *latencyHistogram) bins(binsPerDecade int) []histogramBin {
+	var out []histogramBin
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		_, hi := histogramBucket(i)
+		upTo := int64(1)
+		if hi > 0 {
+			step := math.Ceil(math.Log10(float64(hi)) * float64(binsPerDecade))
+			upTo = int64(math.Round(math.Pow(10, step/float64(binsPerDecade))))
+		}
+		if n := len(out); n > 0 && out[n-1].UpTo == upTo {
+			out[n-1].Count += c
+		} else {
+			out = append(out, histogramBin{upTo, c})
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 1000, 65535, 1 << 20, 123456789, 1 << 40} {
+		lo, hi := histogramBucket(histogramIndex(v))
+		if v < lo || v > hi {
+			t.Fatalf("%d recorded into bucket [%d, %d]", v, lo, hi)
+		}
+		if v >= 128 && float64(hi-lo+1)/float64(v) > 1.0/64 {
+			t.Fatalf("bucket [%d, %d] of %d is wider than 1/64", lo, hi, v)
+		}
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	var h latencyHistogram
+	for v := int64(1); v <= 10000; v++ {
+		h.record(v)
+	}
+	for _, c := range []struct {
+		p    float64
+		want int64
+	}{{50, 5000}, {90, 9000}, {99, 9900}, {99.9, 9990}, {100, 10000}} {
+		got := h.percentile(c.p)
+		if got < c.want || float64(got-c.want)/float64(c.want) > 1.0/64 {
+			t.Errorf("p%v = %d, want %d within 1/64", c.p, got, c.want)
+		}
+	}
+	if h.max != 10000 || h.min != 1 || h.mean() != 5000.5 {
+		t.Errorf("min %d max %d mean %v", h.min, h.max, h.mean())
+	}
+	var total int64
+	for _, b := range h.bins(4) {
+		total += b.Count
+	}
+	if total != h.total {
+		t.Errorf("bins hold %d values, recorded %d", total, h.total)
+	}
+}
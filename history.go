@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const glispModule = "github.com/qjpcpu/glisp"
+
+// benchHistory is the history file: one glisp-only run per glisp version,
+// in release order.
+type benchHistory struct {
+	Entries []historyEntry `json:"entries"`
+}
+
+// historyEntry is the run of one version. Versions whose benchmarks do not
+// build or run keep the error instead, so the history shows the gap.
+type historyEntry struct {
+	Version string    `json:"version"`
+	Run     *benchRun `json:"run,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func loadHistory(file string) (*benchHistory, error) {
+	var h benchHistory
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &h, nil
+}
+
+func (h *benchHistory) save(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// set replaces the entry of e.Version, or appends it.
+func (h *benchHistory) set(e historyEntry) {
+	for i := range h.Entries {
+		if h.Entries[i].Version == e.Version {
+			h.Entries[i] = e
+			return
+		}
+	}
+	h.Entries = append(h.Entries, e)
+}
+
+// runVersion runs the glisp benchmarks with glisp pinned to version. The
+// module is copied to a temporary directory, where go get pins the version
+// and the copy's own run command does the measuring, so that every version
+// is built from the same benchmark sources.
+func runVersion(version string, args []string) historyEntry {
+	entry := historyEntry{Version: version}
+	dir, err := os.MkdirTemp("", "glisp-history-")
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	defer os.RemoveAll(dir)
+	if err := copyModule(".", dir); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if err := goCommand(dir, "get", glispModule+"@"+version); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	out := filepath.Join(dir, "run.json")
+	run := append([]string{"run", ".", "run", "-engine", "glisp", "-q", "-dir", "", "-format", "json", "-o", out}, args...)
+	if err := goCommand(dir, run...); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if entry.Run, err = loadRun(out); err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+func goCommand(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go %s: %v\n%s", args[0], err, stderr.String())
+	}
+	return nil
+}
+
+// copyModule copies the module in src to dst, leaving out version control,
+// results and generated documentation.
+func copyModule(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || rel == "results" || rel == "docs") {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0644)
+	})
+}
+
+// trend is the ns/op of one workload in every version of a history, zero
+// where a version has no result. change is the difference to the previous
+// version with a result, in percent.
+type trend struct {
+	Workload string    `json:"workload"`
+	NsPerOp  []float64 `json:"ns_per_op"`
+	Change   []float64 `json:"change_percent"`
+}
+
+func (h *benchHistory) versions() []string {
+	versions := make([]string, len(h.Entries))
+	for i, e := range h.Entries {
+		versions[i] = e.Version
+	}
+	return versions
+}
+
+func (h *benchHistory) trends() []trend {
+	var trends []trend
+	index := make(map[string]int)
+	for i, e := range h.Entries {
+		if e.Run == nil {
+			continue
+		}
+		for _, s := range summarize(e.Run.Results) {
+			if s.Engine != "glisp" || s.Failed {
+				continue
+			}
+			j, ok := index[s.Workload]
+			if !ok {
+				j = len(trends)
+				index[s.Workload] = j
+				trends = append(trends, trend{
+					Workload: s.Workload,
+					NsPerOp:  make([]float64, len(h.Entries)),
+					Change:   make([]float64, len(h.Entries)),
+				})
+			}
+			trends[j].NsPerOp[i] = s.NsPerOp
+		}
+	}
+	for _, t := range trends {
+		prev := 0.0
+		for i, ns := range t.NsPerOp {
+			if ns == 0 {
+				continue
+			}
+			if prev > 0 {
+				t.Change[i] = (ns - prev) / prev * 100
+			}
+			prev = ns
+		}
+	}
+	return trends
+}
+
+// historyTable has a row per workload and a column per version. Changes
+// beyond threshold percent are bold and listed in the last column, as the
+// release that introduced an improvement (-) or regression (+).
+func historyTable(h *benchHistory, trends []trend, threshold float64) *textTable {
+	t := &textTable{header: []string{"Benchmark"}, bold: make(map[[2]int]bool)}
+	for _, e := range h.Entries {
+		label := e.Version + " (ns/op)"
+		if e.Run == nil {
+			label = e.Version + " (failed)"
+		}
+		t.header = append(t.header, label)
+	}
+	t.header = append(t.header, "Changes")
+	for i, tr := range trends {
+		row := []string{tr.Workload}
+		var changes []string
+		for j, ns := range tr.NsPerOp {
+			if ns == 0 {
+				row = append(row, "N/A")
+				continue
+			}
+			cell := formatNs(ns)
+			if math.Abs(tr.Change[j]) >= threshold {
+				t.bold[[2]int{i, j + 1}] = true
+				cell += fmt.Sprintf(" (%+.1f%%)", tr.Change[j])
+				changes = append(changes, fmt.Sprintf("%s %+.1f%%", h.Entries[j].Version, tr.Change[j]))
+			}
+			row = append(row, cell)
+		}
+		row = append(row, strings.Join(changes, ", "))
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// writeTrendCharts writes one chart per workload to dir and returns the
+// file names.
+func writeTrendCharts(dir string, versions []string, trends []trend, threshold float64) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, tr := range trends {
+		file := filepath.Join(dir, unsafeFileChars.ReplaceAllString(tr.Workload, "_")+".svg")
+		chart := trendChart(tr.Workload, "ns/op", versions, tr.NsPerOp, tr.Change, threshold)
+		if err := os.WriteFile(file, []byte(chart), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func parseVersions(list string) []string {
+	var versions []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// historyArgs are the run flags passed on to every version.
+func historyArgs(workload, cases, benchtime string, count int) []string {
+	args := []string{"-benchtime", benchtime, "-count", strconv.Itoa(count)}
+	if workload != "" {
+		args = append(args, "-workload", workload)
+	}
+	if cases != "" {
+		args = append(args, "-case", cases)
+	}
+	return args
+}
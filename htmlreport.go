@@ -0,0 +1,288 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// htmlReport is the data behind reportTemplate: a single self-contained
+// page per run, meant for people who do not read markdown tables.
+type htmlReport struct {
+	Run *benchRun
+	// Nav is the navigation and filter bar of pages served by serve.
+	Nav     template.HTML
+	Modules [][2]string
+	Header  []string
+	Rows    [][]htmlCell
+	// Macro is the engine table of the Benchmarks Game programs, kept
+	// apart from the micro-benchmarks of Rows.
+	Macro     *htmlSummary
+	Workloads []htmlWorkload
+	// Latency is the percentile table and histograms of the latency
+	// command, if the run has them.
+	Latency       *htmlTable
+	LatencyCharts []template.HTML
+}
+
+type htmlTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+type htmlSummary struct {
+	Header []string
+	Rows   [][]htmlCell
+}
+
+type htmlCell struct {
+	Text string
+	Bold bool
+}
+
+type htmlWorkload struct {
+	Name    string
+	Charts  []template.HTML
+	Scaling []template.HTML
+	Sources []sourceListing
+}
+
+// sizeLadder matches the n=<size> element of sub-benchmarks run at several
+// sizes, such as HashScale_glisp/string/n=1000/insert.
+var sizeLadder = regexp.MustCompile(`(^|/)n=(\d+)(/|$)`)
+
+// ladderPoint is one size of a scaling series.
+type ladderPoint struct {
+	size   float64
+	engine string
+	ns     float64
+}
+
+func newHTMLReport(run *benchRun) *htmlReport {
+	summaries := summarize(run.Results)
+	engines := engineOrder(summaries)
+	report := &htmlReport{Run: run}
+	micro, macro := splitMacro(summaries)
+	if len(micro) > 0 {
+		report.Header, report.Rows = htmlRows(micro)
+	}
+	if len(macro) > 0 {
+		report.Macro = &htmlSummary{}
+		report.Macro.Header, report.Macro.Rows = htmlRows(macro)
+	}
+	for path, version := range run.Modules {
+		report.Modules = append(report.Modules, [2]string{path, version})
+	}
+	sort.Slice(report.Modules, func(i, j int) bool { return report.Modules[i][0] < report.Modules[j][0] })
+
+	procs := make(map[int]bool)
+	for _, s := range summaries {
+		procs[s.Procs] = true
+	}
+	// Rows keep the order of summaries: one bar chart pair per workload,
+	// or one line chart per ladder with the size taken out of the name.
+	type row struct {
+		name   string
+		ladder bool
+		byEng  map[string]summary
+		points []ladderPoint
+	}
+	var rows []*row
+	rowIndex := make(map[string]*row)
+	workloadIndex := make(map[string]int)
+	for _, s := range summaries {
+		name := s.Workload
+		m := sizeLadder.FindStringSubmatchIndex(name)
+		if m != nil {
+			name = name[:m[4]-2] + "n=*" + name[m[5]:]
+		}
+		if len(procs) > 1 {
+			name += "-" + strconv.Itoa(s.Procs)
+		}
+		r, ok := rowIndex[name]
+		if !ok {
+			r = &row{name: name, ladder: m != nil, byEng: make(map[string]summary)}
+			rowIndex[name] = r
+			rows = append(rows, r)
+		}
+		if s.Failed {
+			continue
+		}
+		if m != nil {
+			size, _ := strconv.ParseFloat(s.Workload[m[4]:m[5]], 64)
+			r.points = append(r.points, ladderPoint{size, s.Engine, s.NsPerOp})
+		} else {
+			r.byEng[s.Engine] = s
+		}
+	}
+	for _, r := range rows {
+		top := strings.SplitN(r.name, "/", 2)[0]
+		i, ok := workloadIndex[top]
+		if !ok {
+			i = len(report.Workloads)
+			workloadIndex[top] = i
+			report.Workloads = append(report.Workloads, htmlWorkload{Name: top})
+		}
+		w := &report.Workloads[i]
+		if r.ladder {
+			w.Scaling = append(w.Scaling, template.HTML(ladderChart(r.name, engines, r.points)))
+			continue
+		}
+		var labels []string
+		var ops, allocs []float64
+		for _, e := range engines {
+			s, ok := r.byEng[e]
+			if !ok {
+				continue
+			}
+			labels = append(labels, e)
+			ops = append(ops, 1e6/s.NsPerOp)
+			allocs = append(allocs, float64(s.AllocsPerOp))
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		w.Charts = append(w.Charts,
+			template.HTML(barChart(r.name, "op/ms", labels, ops)),
+			template.HTML(barChart(r.name, "allocs/op", labels, allocs)))
+	}
+	for _, listing := range workloadSources(summaries) {
+		top := strings.SplitN(listing.Workload, "/", 2)[0]
+		if i, ok := workloadIndex[top]; ok {
+			report.Workloads[i].Sources = append(report.Workloads[i].Sources, listing)
+		}
+	}
+	if len(run.Latency) > 0 {
+		t := latencyTable(run.Latency)
+		report.Latency = &htmlTable{Header: t.header, Rows: t.rows}
+		var order []string
+		byWorkload := make(map[string][]latencyResult)
+		for _, r := range run.Latency {
+			if r.Error != "" {
+				continue
+			}
+			if _, ok := byWorkload[r.Workload]; !ok {
+				order = append(order, r.Workload)
+			}
+			byWorkload[r.Workload] = append(byWorkload[r.Workload], r)
+		}
+		for _, w := range order {
+			report.LatencyCharts = append(report.LatencyCharts, template.HTML(latencyChart(w, byWorkload[w])))
+		}
+	}
+	return report
+}
+
+// htmlRows is the engine table of summaries in ns/op, with the bold cells
+// marked.
+func htmlRows(summaries []summary) ([]string, [][]htmlCell) {
+	table, _ := engineTable(summaries, "ns/op")
+	var rows [][]htmlCell
+	for i, row := range table.rows {
+		cells := make([]htmlCell, len(row))
+		for j, text := range row {
+			cells[j] = htmlCell{text, table.bold[[2]int{i, j}]}
+		}
+		rows = append(rows, cells)
+	}
+	return table.header, rows
+}
+
+// ladderChart plots ns/op against size, one line per engine.
+func ladderChart(name string, engines []string, points []ladderPoint) string {
+	var sizes []float64
+	seen := make(map[float64]bool)
+	for _, p := range points {
+		if !seen[p.size] {
+			seen[p.size] = true
+			sizes = append(sizes, p.size)
+		}
+	}
+	sort.Float64s(sizes)
+	var series []chartSeries
+	for _, e := range engines {
+		s := chartSeries{Name: e, Values: make([]float64, len(sizes))}
+		found := false
+		for _, p := range points {
+			if p.engine == e {
+				s.Values[sort.SearchFloat64s(sizes, p.size)] = p.ns
+				found = true
+			}
+		}
+		if found {
+			series = append(series, s)
+		}
+	}
+	return lineChart(name, "ns/op", "n", sizes, series)
+}
+
+func writeHTMLReport(w io.Writer, run *benchRun) error {
+	return reportTemplate.Execute(w, newHTMLReport(run))
+}
+
+// reportStyle is shared by the report and the pages of the serve command.
+const reportStyle = `body { font-family: sans-serif; margin: 2em auto; max-width: 1100px; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: 3px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.charts { display: flex; flex-wrap: wrap; gap: 8px; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; font-size: 12px; }
+details { margin: 4px 0; }
+summary { cursor: pointer; }
+`
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>glisp-benchmark {{.Run.ID}}</title>
+<style>
+` + reportStyle + `</style>
+</head>
+<body>
+{{.Nav}}
+<h1>glisp-benchmark {{.Run.ID}}</h1>
+<table>
+<tr><th>Started</th><td>{{.Run.Started.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+<tr><th>Go</th><td>{{.Run.GoVersion}} {{.Run.GOOS}}/{{.Run.GOARCH}}</td></tr>
+<tr><th>CPUs</th><td>{{.Run.NumCPU}}</td></tr>
+<tr><th>Benchtime</th><td>{{.Run.Benchtime}} &times; {{.Run.Count}}</td></tr>
+{{range .Modules}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+
+{{if .Rows}}<h2>Summary</h2>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{if .Bold}}<b>{{.Text}}</b>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{end}}</table>{{end}}
+
+{{with .Macro}}<h2>Macro Benchmarks</h2>
+<p>Ports of Computer Language Benchmarks Game programs at small fixed sizes.</p>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{if .Bold}}<b>{{.Text}}</b>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{end}}</table>{{end}}
+
+{{with .Latency}}<h2>Latency</h2>
+<p>Per-call latency in ns.</p>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{end}}
+{{if .LatencyCharts}}<div class="charts">{{range .LatencyCharts}}{{.}}{{end}}</div>{{end}}
+
+{{range .Workloads}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+{{if .Charts}}<div class="charts">{{range .Charts}}{{.}}{{end}}</div>{{end}}
+{{if .Scaling}}<h3>Scaling</h3>
+<div class="charts">{{range .Scaling}}{{.}}{{end}}</div>{{end}}
+{{range .Sources}}<details><summary>Source of {{.Workload}}</summary>
+{{range .Engines}}<details><summary>{{.Engine}}</summary><pre>{{.Source}}</pre></details>
+{{end}}</details>
+{{end}}{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// latencyOptions configures measureLatency: warmup untimed calls, then
+// calls timed one by one.
+type latencyOptions struct {
+	warmup int
+	calls  int
+}
+
+// latencyPercentiles are the points of the latency distribution reported
+// for every workload, in percent.
+var latencyPercentiles = []float64{50, 90, 99, 99.9}
+
+// latencyResult is the per-call latency distribution of one workload in
+// one engine, in ns. GCs counts the collections that ran during the timed
+// calls; their pauses land in the calls they interrupt, so they show up in
+// the tail instead of being averaged away.
+type latencyResult struct {
+	Workload    string         `json:"workload"`
+	Engine      string         `json:"engine"`
+	Calls       int64          `json:"calls"`
+	Mean        float64        `json:"mean_ns"`
+	Percentiles []int64        `json:"percentiles_ns"`
+	Max         int64          `json:"max_ns"`
+	GCs         uint32         `json:"gcs"`
+	Histogram   []histogramBin `json:"histogram"`
+	Error       string         `json:"error,omitempty"`
+}
+
+func measureLatency(w workload, engine string, opts latencyOptions) latencyResult {
+	res := latencyResult{Workload: w.name, Engine: engine}
+	call, err := w.prepare(engine)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for i := 0; i < opts.warmup; i++ {
+		if _, err := call(); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+	var h latencyHistogram
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < opts.calls; i++ {
+		start := time.Now()
+		got, err := call()
+		h.record(int64(time.Since(start)))
+		if err == nil && got != w.expect {
+			err = fmt.Errorf("got %s, want %s", got, w.expect)
+		}
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+	runtime.ReadMemStats(&after)
+	res.Calls = h.total
+	res.Mean = h.mean()
+	for _, p := range latencyPercentiles {
+		res.Percentiles = append(res.Percentiles, h.percentile(p))
+	}
+	res.Max = h.max
+	res.GCs = after.NumGC - before.NumGC
+	res.Histogram = h.bins(4)
+	return res
+}
+
+// runLatency measures every testdata workload matching filter in every
+// selected engine that has a script for it.
+func runLatency(filter *regexp.Regexp, engines map[string]bool, opts latencyOptions, progress func(latencyResult)) ([]latencyResult, error) {
+	var results []latencyResult
+	err := eachWorkload(filter, engines, func(w workload, engine string) {
+		res := measureLatency(w, engine, opts)
+		results = append(results, res)
+		if progress != nil {
+			progress(res)
+		}
+	})
+	return results, err
+}
+
+// latencyTable is the percentile table of a run, in ns.
+func latencyTable(results []latencyResult) *textTable {
+	t := &textTable{header: []string{"Workload", "Engine", "calls", "mean"}}
+	for _, p := range latencyPercentiles {
+		t.header = append(t.header, "p"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	t.header = append(t.header, "max", "GCs")
+	for _, r := range results {
+		row := []string{r.Workload, r.Engine}
+		if r.Error != "" {
+			row = append(row, "FAIL: "+r.Error)
+			for len(row) < len(t.header) {
+				row = append(row, "")
+			}
+			t.rows = append(t.rows, row)
+			continue
+		}
+		row = append(row, strconv.FormatInt(r.Calls, 10), formatNs(r.Mean))
+		for _, v := range r.Percentiles {
+			row = append(row, formatNs(float64(v)))
+		}
+		row = append(row, formatNs(float64(r.Max)), strconv.FormatUint(uint64(r.GCs), 10))
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// latencyChart plots the histograms of every engine for one workload.
+func latencyChart(workload string, results []latencyResult) string {
+	var xs []float64
+	seen := make(map[int64]bool)
+	for _, r := range results {
+		for _, b := range r.Histogram {
+			if !seen[b.UpTo] {
+				seen[b.UpTo] = true
+				xs = append(xs, float64(b.UpTo))
+			}
+		}
+	}
+	sort.Float64s(xs)
+	var series []chartSeries
+	for _, r := range results {
+		s := chartSeries{Name: r.Engine, Values: make([]float64, len(xs))}
+		for _, b := range r.Histogram {
+			for i, x := range xs {
+				if x == float64(b.UpTo) {
+					s.Values[i] = float64(b.Count)
+				}
+			}
+		}
+		series = append(series, s)
+	}
+	return lineChart(workload+" latency", "calls", "ns", xs, series)
+}
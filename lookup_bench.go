@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// lookupGlobals are the numbers of global functions defined next to
+// target, to show whether looking a function up by name slows down as the
+// global scope grows.
+var lookupGlobals = []int{10, 100, 1000, 10000}
+
+// lookupScript defines n filler functions rule_<i>(x) and target(x), which
+// returns x + 1, with def formatting one function definition.
+func lookupScript(n int, def func(name, body string) string, add func(i int) string) string {
+	var src strings.Builder
+	for i := 0; i < n; i++ {
+		src.WriteString(def(fmt.Sprintf("rule_%d", i), add(i)))
+	}
+	src.WriteString(def("target", add(1)))
+	return src.String()
+}
+
+// benchmarkLookup runs a by-name and a cached sub-benchmark per number of
+// globals. load builds an engine holding lookupScript(n) and returns a call
+// of target that looks it up by name every time, and one through a handle
+// resolved once.
+func benchmarkLookup(t *testing.B, load func(n int) (byName, cached func(x int64) (int64, error), err error)) {
+	const x = 41
+	for _, n := range lookupGlobals {
+		byName, cached, err := load(n)
+		MustSuccess(t, err)
+		for _, c := range []struct {
+			name string
+			call func(x int64) (int64, error)
+		}{{"by-name", byName}, {"cached", cached}} {
+			subBenchmark(t, fmt.Sprintf("n=%d/%s", n, c.name), func(t *testing.B) {
+				for i := 0; i < t.N; i++ {
+					v, err := c.call(x)
+					MustSuccess(t, err)
+					MustEqualInt64(t, x+1, v)
+				}
+			})
+		}
+	}
+}
+
+func benchFunctionLookup_glisp(t *testing.B) {
+	benchmarkLookup(t, func(n int) (func(int64) (int64, error), func(int64) (int64, error), error) {
+		vm := glisp.New()
+		ext.ImportCoreUtils(vm)
+		src := lookupScript(n, func(name, body string) string {
+			return fmt.Sprintf("(defn %s [x] %s)\n", name, body)
+		}, func(i int) string { return fmt.Sprintf("(+ x %d)", i) })
+		if err := vm.SourceStream(bytes.NewBufferString(src)); err != nil {
+			return nil, nil, err
+		}
+		v, ok := vm.FindObject("target")
+		if !ok {
+			return nil, nil, fmt.Errorf("target not found")
+		}
+		fn, ok := v.(*glisp.SexpFunction)
+		if !ok {
+			return nil, nil, fmt.Errorf("target is not a function")
+		}
+		result := func(v glisp.Sexp, err error) (int64, error) {
+			if err != nil {
+				return 0, err
+			}
+			n, ok := v.(glisp.SexpInt)
+			if !ok {
+				return 0, fmt.Errorf("target returned %s", v.SexpString())
+			}
+			return n.ToInt64(), nil
+		}
+		return func(x int64) (int64, error) {
+				return result(vm.ApplyByName("target", glisp.MakeArgs(glisp.NewSexpInt64(x))))
+			}, func(x int64) (int64, error) {
+				return result(vm.Apply(fn, glisp.MakeArgs(glisp.NewSexpInt64(x))))
+			}, nil
+	})
+}
+
+func benchFunctionLookup_goja(t *testing.B) {
+	benchmarkLookup(t, func(n int) (func(int64) (int64, error), func(int64) (int64, error), error) {
+		vm := goja.New()
+		src := lookupScript(n, func(name, body string) string {
+			return fmt.Sprintf("function %s(x) { return %s; }\n", name, body)
+		}, func(i int) string { return fmt.Sprintf("x + %d", i) })
+		if _, err := vm.RunString(src); err != nil {
+			return nil, nil, err
+		}
+		fn, ok := goja.AssertFunction(vm.Get("target"))
+		if !ok {
+			return nil, nil, fmt.Errorf("target is not a function")
+		}
+		return func(x int64) (int64, error) {
+				f, ok := goja.AssertFunction(vm.Get("target"))
+				if !ok {
+					return 0, fmt.Errorf("target is not a function")
+				}
+				res, err := f(goja.Undefined(), vm.ToValue(x))
+				if err != nil {
+					return 0, err
+				}
+				return res.ToInteger(), nil
+			}, func(x int64) (int64, error) {
+				res, err := fn(goja.Undefined(), vm.ToValue(x))
+				if err != nil {
+					return 0, err
+				}
+				return res.ToInteger(), nil
+			}, nil
+	})
+}
+
+func benchFunctionLookup_lua(t *testing.B) {
+	benchmarkLookup(t, func(n int) (func(int64) (int64, error), func(int64) (int64, error), error) {
+		l := lua.NewState()
+		lua.OpenLibraries(l)
+		src := lookupScript(n, func(name, body string) string {
+			return fmt.Sprintf("function %s(x) return %s end\n", name, body)
+		}, func(i int) string { return fmt.Sprintf("x + %d", i) })
+		if err := lua.DoString(l, src); err != nil {
+			return nil, nil, err
+		}
+		// go-lua has no references, so the cached handle is target kept at
+		// the bottom of the stack and copied to the top for every call.
+		l.Global("target")
+		call := func(x int64) (int64, error) {
+			l.PushInteger(int(x))
+			if err := l.ProtectedCall(1, 1, 0); err != nil {
+				l.Pop(1)
+				return 0, err
+			}
+			defer l.Pop(1)
+			v, ok := l.ToInteger(-1)
+			if !ok {
+				return 0, fmt.Errorf("target did not return an integer")
+			}
+			return int64(v), nil
+		}
+		return func(x int64) (int64, error) {
+				l.Global("target")
+				return call(x)
+			}, func(x int64) (int64, error) {
+				l.PushValue(1)
+				return call(x)
+			}, nil
+	})
+}
+
+func benchFunctionLookup_zygo(t *testing.B) {
+	benchmarkLookup(t, func(n int) (func(int64) (int64, error), func(int64) (int64, error), error) {
+		env := zygo.NewZlisp()
+		src := lookupScript(n, func(name, body string) string {
+			return fmt.Sprintf("(defn %s [x] %s)\n", name, body)
+		}, func(i int) string { return fmt.Sprintf("(+ x %d)", i) })
+		if _, err := env.EvalString(src); err != nil {
+			return nil, nil, err
+		}
+		find := func() (*zygo.SexpFunction, error) {
+			v, ok := env.FindObject("target")
+			if !ok {
+				return nil, fmt.Errorf("target not found")
+			}
+			fn, ok := v.(*zygo.SexpFunction)
+			if !ok {
+				return nil, fmt.Errorf("target is not a function")
+			}
+			return fn, nil
+		}
+		call := func(fn *zygo.SexpFunction, x int64) (int64, error) {
+			res, err := env.Apply(fn, []zygo.Sexp{&zygo.SexpInt{Val: x}})
+			if err != nil {
+				return 0, err
+			}
+			n, ok := res.(*zygo.SexpInt)
+			if !ok {
+				return 0, fmt.Errorf("target returned %s", res.SexpString(nil))
+			}
+			return n.Val, nil
+		}
+		fn, err := find()
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(x int64) (int64, error) {
+				fn, err := find()
+				if err != nil {
+					return 0, err
+				}
+				return call(fn, x)
+			}, func(x int64) (int64, error) {
+				return call(fn, x)
+			}, nil
+	})
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/Shopify/go-lua"
 )
 
-func BenchmarkFactorial_lua(t *testing.B) {
+func benchFactorial_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 
@@ -37,7 +37,7 @@ func BenchmarkFactorial_lua(t *testing.B) {
 	}
 }
 
-func BenchmarkHashWrite_lua(t *testing.B) {
+func benchHashWrite_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	script := `
@@ -70,7 +70,7 @@ end
 	}
 }
 
-func BenchmarkHashDelete_lua(t *testing.B) {
+func benchHashDelete_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	script := `
@@ -101,7 +101,7 @@ end
 	}
 }
 
-func BenchmarkRegexpMatch_lua(t *testing.B) {
+func benchRegexpMatch_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	var cache sync.Map
@@ -147,7 +147,7 @@ func BenchmarkRegexpMatch_lua(t *testing.B) {
 	}
 }
 
-func BenchmarkComplexCondition_lua(t *testing.B) {
+func benchComplexCondition_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	script := `
@@ -177,7 +177,7 @@ end
 	}
 }
 
-func BenchmarkFormatTime_lua(t *testing.B) {
+func benchFormatTime_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	l.Register("format", func(l *lua.State) int {
@@ -211,7 +211,7 @@ end
 	}
 }
 
-func BenchmarkHashAccess_lua(t *testing.B) {
+func benchHashAccess_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	script := `
@@ -246,7 +246,7 @@ end
 	}
 }
 
-func BenchmarkJSONParseAndModify_lua(t *testing.B) {
+func benchJSONParseAndModify_lua(t *testing.B) {
 	l := lua.NewState()
 	lua.OpenLibraries(l)
 	l.Register("parse_and_modify", func(l *lua.State) int {
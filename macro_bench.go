@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+// The macro benchmarks are ports of Computer Language Benchmarks Game
+// programs at small fixed sizes, under testdata/macro. Reports keep them
+// apart from the micro benchmarks.
+
+func benchMacro_glisp(t *testing.B) {
+	benchmarkWorkloads(t, "testdata/macro", "glisp")
+}
+
+func benchMacro_goja(t *testing.B) {
+	benchmarkWorkloads(t, "testdata/macro", "goja")
+}
+
+func benchMacro_lua(t *testing.B) {
+	benchmarkWorkloads(t, "testdata/macro", "lua")
+}
+
+func benchMacro_zygo(t *testing.B) {
+	benchmarkWorkloads(t, "testdata/macro", "zygo")
+}
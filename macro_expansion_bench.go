@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// The macro expansion workload only runs on the Lisp engines. A script of
+// expansionRules rules is written twice: once with a defrule DSL form,
+// when/unless and a threading macro, and once as the hand-written code the
+// macros stand for. Rule k matches x when x >= k and (3x + k) mod 7 is 0,
+// and evaluate counts the rules matching expansionInput.
+const (
+	expansionRules = 200
+	expansionInput = 150
+)
+
+func expectedExpansion() int64 {
+	var n int64
+	for k := int64(0); k < expansionRules; k++ {
+		if expansionInput >= k && (3*expansionInput+k)%7 == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// expansionScript writes the rules with rule and appends the rule table
+// and evaluate to prelude.
+func expansionScript(prelude string, rule func(k int) string, table func(names []string) string) string {
+	var src strings.Builder
+	src.WriteString(prelude)
+	names := make([]string, expansionRules)
+	for k := range names {
+		names[k] = fmt.Sprintf("rule_%d", k)
+		src.WriteString(rule(k))
+	}
+	src.WriteString(table(names))
+	return src.String()
+}
+
+// expansionEngine is a fresh engine: load evaluates a script and evaluate
+// calls its evaluate function.
+type expansionEngine struct {
+	load     func(src string) error
+	evaluate func(x int64) (int64, error)
+}
+
+// benchmarkExpansion times loading each script into a fresh engine, which
+// is where the macros are expanded, then calling the loaded rules. The
+// engine is created with the timer stopped, so load/macros against
+// load/hand-written is the cost of expansion.
+func benchmarkExpansion(t *testing.B, macros, handWritten string, newEngine func() expansionEngine) {
+	want := expectedExpansion()
+	scripts := []struct {
+		name string
+		src  string
+	}{{"macros", macros}, {"hand-written", handWritten}}
+	for _, s := range scripts {
+		subBenchmark(t, "load/"+s.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				t.StopTimer()
+				e := newEngine()
+				t.StartTimer()
+				MustSuccess(t, e.load(s.src))
+			}
+		})
+	}
+	for _, s := range scripts {
+		e := newEngine()
+		MustSuccess(t, e.load(s.src))
+		subBenchmark(t, "call/"+s.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				got, err := e.evaluate(expansionInput)
+				MustSuccess(t, err)
+				MustEqualInt64(t, want, got)
+			}
+		})
+	}
+}
+
+func benchMacroExpansion_glisp(t *testing.B) {
+	// when, unless and -> come from the core library, itself written
+	// with defmac.
+	table := func(names []string) string {
+		return "(def rules [" + strings.Join(names, " ") + `])
+
+(defn evaluate [x]
+  (foldl (fn [r acc] (+ acc (r x))) 0 rules))
+`
+	}
+	macros := expansionScript("(defmac defrule [name arg & body]\n  `(defn ~name [~arg] (cond (begin ~@body) 1 0)))\n\n",
+		func(k int) string {
+			guard := fmt.Sprintf("unless (< x %d)", k)
+			if k%2 == 1 {
+				guard = fmt.Sprintf("when (>= x %d)", k)
+			}
+			return fmt.Sprintf("(defrule rule_%d x\n  (%s\n    (-> x (* 3) (+ %d) (mod 7) (= 0))))\n\n", k, guard, k)
+		}, table)
+	handWritten := expansionScript("", func(k int) string {
+		return fmt.Sprintf("(defn rule_%d [x]\n  (cond (and (>= x %d) (= 0 (mod (+ (* x 3) %d) 7))) 1 0))\n\n", k, k, k)
+	}, table)
+	benchmarkExpansion(t, macros, handWritten, func() expansionEngine {
+		vm := glisp.New()
+		ext.ImportCoreUtils(vm)
+		return expansionEngine{
+			load: func(src string) error {
+				return vm.SourceStream(bytes.NewBufferString(src))
+			},
+			evaluate: func(x int64) (int64, error) {
+				v, err := vm.ApplyByName("evaluate", glisp.MakeArgs(glisp.NewSexpInt64(x)))
+				if err != nil {
+					return 0, err
+				}
+				n, ok := v.(glisp.SexpInt)
+				if !ok {
+					return 0, fmt.Errorf("evaluate returned %s", v.SexpString())
+				}
+				return n.ToInt64(), nil
+			},
+		}
+	})
+}
+
+func benchMacroExpansion_zygo(t *testing.B) {
+	// zygo has no when, unless or threading macro (its -> is a function
+	// over hashes), so the script defines them.
+	const prelude = `(defmac when [c & body] ^(cond ~c (begin ~@body) nil))
+
+(defmac unless [c & body] ^(cond ~c nil (begin ~@body)))
+
+(defmac thread [x & forms]
+  (cond (empty? forms) x
+        (letseq [form (car forms) head (car form) tail (cdr form) more (cdr forms)]
+          ^(thread (~head ~x ~@tail) ~@more))))
+
+(defmac defrule [name arg & body] ^(defn ~name [~arg] (cond (begin ~@body) 1 0)))
+
+`
+	table := func(names []string) string {
+		return "(def rules [" + strings.Join(names, " ") + `])
+
+(defn evaluate [x]
+  (def n 0)
+  (for [(def i 0) (< i (len rules)) (set i (+ i 1))]
+    (set n (+ n ((aget rules i) x))))
+  n)
+`
+	}
+	macros := expansionScript(prelude, func(k int) string {
+		guard := fmt.Sprintf("unless (< x %d)", k)
+		if k%2 == 1 {
+			guard = fmt.Sprintf("when (>= x %d)", k)
+		}
+		return fmt.Sprintf("(defrule rule_%d x\n  (%s\n    (thread x (* 3) (+ %d) (mod 7) (== 0))))\n\n", k, guard, k)
+	}, table)
+	handWritten := expansionScript("", func(k int) string {
+		return fmt.Sprintf("(defn rule_%d [x]\n  (cond (and (>= x %d) (== 0 (mod (+ (* x 3) %d) 7))) 1 0))\n\n", k, k, k)
+	}, table)
+	benchmarkExpansion(t, macros, handWritten, func() expansionEngine {
+		env := zygo.NewZlisp()
+		return expansionEngine{
+			load: func(src string) error {
+				_, err := env.EvalString(src)
+				return err
+			},
+			evaluate: func(x int64) (int64, error) {
+				v, ok := env.FindObject("evaluate")
+				if !ok {
+					return 0, fmt.Errorf("evaluate not found")
+				}
+				res, err := env.Apply(v.(*zygo.SexpFunction), []zygo.Sexp{&zygo.SexpInt{Val: x}})
+				if err != nil {
+					return 0, err
+				}
+				n, ok := res.(*zygo.SexpInt)
+				if !ok {
+					return 0, fmt.Errorf("evaluate returned %s", res.SexpString(nil))
+				}
+				return n.Val, nil
+			},
+		}
+	})
+}
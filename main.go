@@ -1,4 +1,519 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const usage = `usage: glisp-benchmark <command> [flags]
+
+commands:
+  run      run benchmarks in-process and save the results
+  report   print a saved run
+  compare  compare two saved runs
+  list     list the available benchmarks
+  history  run the glisp benchmarks against several glisp releases
+  serve    browse, filter and compare saved runs in a web browser
+  cold     time the first calls after loading a workload against the steady state
+  latency  record per-call latency percentiles and save them as a run
+  sweep    rerun benchmarks under several GOGC and GOMEMLIMIT settings
+
+Run glisp-benchmark <command> -h for the flags of a command.
+`
+
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	commands := map[string]func(args []string) error{
+		"run":     runCommand,
+		"report":  reportCommand,
+		"compare": compareCommand,
+		"list":    listCommand,
+		"history": historyCommand,
+		"serve":   serveCommand,
+		"cold":    coldCommand,
+		"latency": latencyCommand,
+		"sweep":   sweepCommand,
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "glisp-benchmark:", err)
+		os.Exit(1)
+	}
+}
+
+// outputFlags are shared by every command that prints results.
+type outputFlags struct {
+	format string
+	output string
+	// metric, stat, chart and logScale only apply to whole runs.
+	metric   string
+	stat     string
+	chart    string
+	logScale bool
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "table", "output format: table, markdown, json, csv, or html for run and report")
+	fs.StringVar(&o.output, "o", "", "write output to this file instead of stdout")
+}
+
+func (o *outputFlags) write(fn func(w io.Writer) error) error {
+	if o.output == "" {
+		return fn(os.Stdout)
+	}
+	f, err := os.Create(o.output)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// registerRun adds the flags of commands that print a whole run.
+func (o *outputFlags) registerRun(fs *flag.FlagSet) {
+	o.register(fs)
+	fs.StringVar(&o.metric, "metric", "ns/op", "metric of the markdown table: ns/op or op/ms")
+	fs.StringVar(&o.stat, "stat", "mean", "how the markdown table and -svg chart combine -count runs: mean or median")
+	fs.StringVar(&o.chart, "svg", "", "also write the markdown table as an op/ms bar chart to this SVG file")
+	fs.BoolVar(&o.logScale, "log", false, "use a log scale for the -svg chart")
+}
+
+// print writes t, or v when the format is json.
+func (o *outputFlags) print(t *textTable, v interface{}) error {
+	return o.write(func(w io.Writer) error {
+		if o.format == "json" {
+			return writeJSON(w, v)
+		}
+		return t.write(w, o.format)
+	})
+}
+
+// printRun writes a whole run: the engine tables of the micro and the macro
+// benchmarks for markdown, a self-contained page for html and every result
+// otherwise, followed by the latency percentiles if the run has any. The
+// -svg chart is drawn from the same summaries as the engine tables.
+func (o *outputFlags) printRun(run *benchRun) error {
+	summaries, err := summarizeStat(run.Results, o.stat)
+	if err != nil {
+		return err
+	}
+	if o.chart != "" {
+		chart := engineChart(summaries, o.logScale)
+		if err := os.WriteFile(o.chart, []byte(chart), 0644); err != nil {
+			return err
+		}
+	}
+	switch o.format {
+	case "json":
+		return o.write(func(w io.Writer) error { return writeJSON(w, run) })
+	case "html":
+		return o.write(func(w io.Writer) error { return writeHTMLReport(w, run) })
+	}
+	var tables []*textTable
+	if len(run.Results) > 0 || len(run.Latency) == 0 {
+		if o.format != "markdown" {
+			tables = append(tables, resultsTable(run.Results))
+		} else {
+			micro, macro := splitMacro(summaries)
+			if len(micro) > 0 || len(macro) == 0 {
+				t, err := engineTable(micro, o.metric)
+				if err != nil {
+					return err
+				}
+				tables = append(tables, t)
+			}
+			if len(macro) > 0 {
+				t, err := engineTable(macro, o.metric)
+				if err != nil {
+					return err
+				}
+				t.header[0] = "Macro benchmark"
+				tables = append(tables, t)
+			}
+		}
+	}
+	if len(run.Latency) > 0 {
+		tables = append(tables, latencyTable(run.Latency))
+	}
+	return o.printTables(tables)
+}
+
+// printTables writes tables one after another, separated by a blank line.
+func (o *outputFlags) printTables(tables []*textTable) error {
+	return o.write(func(w io.Writer) error {
+		for i, t := range tables {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			if err := t.write(w, o.format); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// filterFlags select benchmarks by workload, sub-benchmark and engine.
+type filterFlags struct {
+	workload string
+	cases    string
+	engines  string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.workload, "workload", "", "only workloads matching this regexp, e.g. 'Hash|JSON'")
+	fs.StringVar(&f.cases, "case", "", "only sub-benchmarks matching this regexp")
+	fs.StringVar(&f.engines, "engine", "", "comma separated engines, e.g. glisp,lua")
+}
+
+func (f *filterFlags) filter() (suiteFilter, error) {
+	var filter suiteFilter
+	var err error
+	if f.workload != "" {
+		if filter.workload, err = regexp.Compile(f.workload); err != nil {
+			return filter, err
+		}
+	}
+	if f.cases != "" {
+		if filter.cases, err = regexp.Compile(f.cases); err != nil {
+			return filter, err
+		}
+	}
+	if f.engines != "" {
+		filter.engines = make(map[string]bool)
+		for _, e := range strings.Split(f.engines, ",") {
+			filter.engines[strings.TrimSpace(e)] = true
+		}
+	}
+	return filter, nil
+}
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	var out outputFlags
+	var sel filterFlags
+	out.registerRun(fs)
+	sel.register(fs)
+	benchtime := fs.String("benchtime", "1s", "run each benchmark for this duration or Nx iterations")
+	count := fs.Int("count", 1, "run each benchmark this many times")
+	cpu := fs.String("cpu", "", "comma separated GOMAXPROCS values (default current)")
+	dir := fs.String("dir", "results", "save the run as JSON in this directory; empty to skip")
+	quiet := fs.Bool("q", false, "do not print progress to stderr")
+	fs.Parse(args)
+
+	filter, err := sel.filter()
+	if err != nil {
+		return err
+	}
+	if len(filter.selected()) == 0 {
+		return fmt.Errorf("no benchmark matches")
+	}
+	cpus, err := parseCPUs(*cpu)
+	if err != nil {
+		return err
+	}
+	run := newBenchRun(*benchtime, *count)
+	progress := func(res benchResult) {
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "%-60s %12s ns/op\n", res.Name, formatNs(res.NsPerOp))
+		}
+	}
+	run.Results, err = runSuite(filter, *benchtime, *count, cpus, progress)
+	if err != nil {
+		return err
+	}
+	if *dir != "" {
+		file, err := saveRun(*dir, run)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "saved", file)
+	}
+	return out.printRun(run)
+}
+
+func parseCPUs(list string) ([]int, error) {
+	if list == "" {
+		return []int{runtime.GOMAXPROCS(0)}, nil
+	}
+	var cpus []int
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid -cpu value %q", s)
+		}
+		cpus = append(cpus, n)
+	}
+	return cpus, nil
+}
+
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	var out outputFlags
+	out.registerRun(fs)
+	dir := fs.String("dir", "results", "results directory used when no run file is given")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: glisp-benchmark report [flags] [run.json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	files, err := runFiles(fs.Args(), *dir, 1)
+	if err != nil {
+		return err
+	}
+	run, err := loadRun(files[0])
+	if err != nil {
+		return err
+	}
+	return out.printRun(run)
+}
+
+func compareCommand(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	var out outputFlags
+	out.register(fs)
+	dir := fs.String("dir", "results", "results directory used when no run files are given")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: glisp-benchmark compare [flags] [old.json new.json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	files, err := runFiles(fs.Args(), *dir, 2)
+	if err != nil {
+		return err
+	}
+	old, err := loadRun(files[0])
+	if err != nil {
+		return err
+	}
+	new, err := loadRun(files[1])
+	if err != nil {
+		return err
+	}
+	comparisons := compareRuns(old, new)
+	return out.print(comparisonTable(comparisons), comparisons)
+}
+
+// runFiles returns the n run files named in args, or the latest n runs
+// in dir when args is empty.
+func runFiles(args []string, dir string, n int) ([]string, error) {
+	if len(args) > 0 {
+		if len(args) != n {
+			return nil, fmt.Errorf("expected %d run files, got %d", n, len(args))
+		}
+		return args, nil
+	}
+	files, err := listRuns(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) < n {
+		return nil, fmt.Errorf("need %d runs in %s, found %d", n, dir, len(files))
+	}
+	return files[len(files)-n:], nil
+}
+
+func listCommand(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	var out outputFlags
+	var sel filterFlags
+	out.register(fs)
+	sel.register(fs)
+	fs.Parse(args)
+	filter, err := sel.filter()
+	if err != nil {
+		return err
+	}
+	type listing struct {
+		Workload string   `json:"workload"`
+		Engines  []string `json:"engines"`
+	}
+	var listings []listing
+	index := make(map[string]int)
+	for _, s := range filter.selected() {
+		i, ok := index[s.workload]
+		if !ok {
+			i = len(listings)
+			index[s.workload] = i
+			listings = append(listings, listing{Workload: s.workload})
+		}
+		listings[i].Engines = append(listings[i].Engines, s.engine)
+	}
+	t := &textTable{header: []string{"Workload", "Engines"}}
+	for _, l := range listings {
+		t.rows = append(t.rows, []string{l.Workload, strings.Join(l.Engines, ",")})
+	}
+	return out.print(t, listings)
+}
+
+func historyCommand(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	var out outputFlags
+	out.register(fs)
+	workload := fs.String("workload", "", "only workloads matching this regexp")
+	cases := fs.String("case", "", "only sub-benchmarks matching this regexp")
+	versions := fs.String("versions", "", "comma separated glisp versions to run, oldest first; none to only report")
+	file := fs.String("file", "history/history.json", "history file the runs are added to")
+	benchtime := fs.String("benchtime", "1s", "run each benchmark for this duration or Nx iterations")
+	count := fs.Int("count", 1, "run each benchmark this many times")
+	threshold := fs.Float64("threshold", 10, "highlight changes of at least this many percent")
+	charts := fs.String("svg", "", "also write a trend chart per workload to this directory")
+	fs.Parse(args)
+
+	h, err := loadHistory(*file)
+	if err != nil {
+		return err
+	}
+	for _, v := range parseVersions(*versions) {
+		fmt.Fprintln(os.Stderr, "running glisp", v)
+		entry := runVersion(v, historyArgs(*workload, *cases, *benchtime, *count))
+		if entry.Error != "" {
+			fmt.Fprintf(os.Stderr, "glisp %s: %s\n", v, entry.Error)
+		}
+		h.set(entry)
+		if err := h.save(*file); err != nil {
+			return err
+		}
+	}
+	if len(h.Entries) == 0 {
+		return fmt.Errorf("%s has no runs; pass -versions", *file)
+	}
+	trends := h.trends()
+	if *charts != "" {
+		if _, err := writeTrendCharts(*charts, h.versions(), trends, *threshold); err != nil {
+			return err
+		}
+	}
+	return out.print(historyTable(h, trends, *threshold), trends)
+}
+
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dir := fs.String("dir", "results", "results directory to serve")
+	addr := fs.String("addr", "localhost:8080", "listen address")
+	fs.Parse(args)
+	server := &resultsServer{dir: *dir}
+	fmt.Fprintf(os.Stderr, "serving %s on http://%s/\n", *dir, *addr)
+	return http.ListenAndServe(*addr, server.handler())
+}
+
+func coldCommand(args []string) error {
+	fs := flag.NewFlagSet("cold", flag.ExitOnError)
+	var out outputFlags
+	var sel filterFlags
+	out.register(fs)
+	sel.register(fs)
+	var opts coldOptions
+	fs.IntVar(&opts.trials, "trials", 20, "fresh engines per workload; the report shows medians")
+	fs.IntVar(&opts.warm, "warm", 4, "calls after the first to show one by one")
+	fs.IntVar(&opts.steady, "steady", 1000, "further calls averaged as the steady state")
+	fs.Parse(args)
+	if opts.trials < 1 || opts.warm < 0 || opts.steady < 1 {
+		return fmt.Errorf("-trials and -steady must be positive, -warm not negative")
+	}
+	filter, err := sel.filter()
+	if err != nil {
+		return err
+	}
+	results, err := runCold(filter.workload, filter.engines, opts, func(r coldResult) {
+		fmt.Fprintf(os.Stderr, "%s_%s\n", r.Workload, r.Engine)
+	})
+	if err != nil {
+		return err
+	}
+	return out.print(coldTable(results, opts.warm), results)
+}
+
+func latencyCommand(args []string) error {
+	fs := flag.NewFlagSet("latency", flag.ExitOnError)
+	var out outputFlags
+	var sel filterFlags
+	out.registerRun(fs)
+	sel.register(fs)
+	var opts latencyOptions
+	fs.IntVar(&opts.warmup, "warmup", 1000, "untimed calls before measuring")
+	fs.IntVar(&opts.calls, "calls", 100000, "calls timed one by one per workload and engine")
+	dir := fs.String("dir", "results", "save the run as JSON in this directory; empty to skip")
+	fs.Parse(args)
+	if opts.calls < 1 || opts.warmup < 0 {
+		return fmt.Errorf("-calls must be positive and -warmup not negative")
+	}
+	filter, err := sel.filter()
+	if err != nil {
+		return err
+	}
+	run := newBenchRun(fmt.Sprintf("%d calls", opts.calls), 1)
+	run.Latency, err = runLatency(filter.workload, filter.engines, opts, func(r latencyResult) {
+		fmt.Fprintf(os.Stderr, "%s_%s\n", r.Workload, r.Engine)
+	})
+	if err != nil {
+		return err
+	}
+	if *dir != "" {
+		file, err := saveRun(*dir, run)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "saved", file)
+	}
+	return out.printRun(run)
+}
+
+func sweepCommand(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	var out outputFlags
+	var sel filterFlags
+	out.register(fs)
+	sel.register(fs)
+	gogc := fs.String("gogc", "50,100,200,off", "comma separated GOGC values")
+	memLimits := fs.String("gomemlimit", "off,512MiB,64MiB", "comma separated GOMEMLIMIT values, each combined with every GOGC value")
+	benchtime := fs.String("benchtime", "1s", "run each benchmark for this duration or Nx iterations")
+	count := fs.Int("count", 1, "run each benchmark this many times")
+	fs.Parse(args)
+
+	settings, err := gcSettings(*gogc, *memLimits)
+	if err != nil {
+		return err
+	}
+	filter, err := sel.filter()
+	if err != nil {
+		return err
+	}
+	if len(filter.selected()) == 0 {
+		return fmt.Errorf("no benchmark matches")
+	}
+	runArgs := historyArgs(sel.workload, sel.cases, *benchtime, *count)
+	if sel.engines != "" {
+		runArgs = append(runArgs, "-engine", sel.engines)
+	}
+	var runs []sweepRun
+	for _, setting := range settings {
+		fmt.Fprintln(os.Stderr, "running with", setting)
+		r := runSetting(setting, runArgs)
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", setting, r.Error)
+		}
+		runs = append(runs, r)
+	}
+	if out.format == "json" {
+		return out.write(func(w io.Writer) error { return writeJSON(w, runs) })
+	}
+	return out.printTables(sweepTables(newSweepMatrix(runs)))
 }
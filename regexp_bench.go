@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// regexpWorkload is one regex operation. Every engine defines fn(subject),
+// which uses a pattern compiled once at load time, and fn_per_call(pattern,
+// subject), which compiles pattern on every call. Both must return expect.
+// The per-call and compile rows pass uniquePattern(pattern, seq) to every
+// engine, since glisp caches each compiled pattern process-wide and would
+// otherwise time a cache hit.
+type regexpWorkload struct {
+	name    string
+	fn      string
+	pattern string
+	subject string
+	expect  string
+}
+
+var regexpWorkloads = []regexpWorkload{
+	{"find-all", "find_all", `\d+`, "order 12 shipped 345 items to 6789 customers", "12|345|6789"},
+	{"capture", "capture", `(\w+)@(\w+)\.com`, "contact alice@example.com today", "alice|example"},
+	{"replace-all", "replace_all", `\s+`, "a  b \t c\n\nd", "a b c d"},
+	{"split", "split_fields", `\s*[,;]\s*`, "red , green;blue ;  yellow", "red|green|blue|yellow"},
+}
+
+// uniquePattern returns pattern with an alternative that never matches the
+// subjects, which hold no NUL bytes, so every seq gives a distinct pattern
+// with the same matches and groups. seq must outlive the b.N rounds.
+func uniquePattern(pattern string, seq int) string {
+	return fmt.Sprintf("%s|\\x00%d", pattern, seq)
+}
+
+func benchRegexpOps_glisp(t *testing.B) {
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	ext.ImportString(vm)
+	ext.ImportRegex(vm)
+	// The regexp extension has no submatch accessor, so capture groups go
+	// through a Go helper: (submatch-re p) compiles p and returns a function
+	// returning the groups of its first match.
+	vm.AddFunction("submatch-re", func(env *glisp.Environment, args glisp.Args) (glisp.Sexp, error) {
+		if args.Len() != 1 {
+			return glisp.WrongNumberArguments("submatch-re", args.Len(), 1)
+		}
+		pattern, ok := args.Get(0).(glisp.SexpStr)
+		if !ok {
+			return glisp.SexpNull, fmt.Errorf("argument of submatch-re should be a string but got %v", glisp.InspectType(args.Get(0)))
+		}
+		re, err := regexp.Compile(string(pattern))
+		if err != nil {
+			return glisp.SexpNull, err
+		}
+		return glisp.MakeUserFunction("submatch", func(env *glisp.Environment, args glisp.Args) (glisp.Sexp, error) {
+			if args.Len() != 1 {
+				return glisp.WrongNumberArguments("submatch", args.Len(), 1)
+			}
+			text, ok := args.Get(0).(glisp.SexpStr)
+			if !ok {
+				return glisp.SexpNull, fmt.Errorf("argument of submatch should be a string but got %v", glisp.InspectType(args.Get(0)))
+			}
+			var groups glisp.SexpArray
+			for _, g := range re.FindStringSubmatch(string(text)) {
+				groups = append(groups, glisp.SexpStr(g))
+			}
+			return groups, nil
+		}), nil
+	})
+	err := vm.SourceStream(bytes.NewBufferString(`
+(def find-all-re (regexp/compile "\\d+"))
+(def capture-re (submatch-re "(\\w+)@(\\w+)\\.com"))
+(def replace-re (regexp/compile "\\s+"))
+(def split-re (regexp/compile "\\s*[,;]\\s*"))
+
+(defn collect-matches [re s acc]
+  (let [loc (regexp/find-index re s)]
+    (cond (empty? loc) acc
+          (collect-matches re (slice s (aget loc 1) (len s)) (append acc (slice s (aget loc 0) (aget loc 1)))))))
+
+(defn collect-fields [re s acc]
+  (let [loc (regexp/find-index re s)]
+    (cond (empty? loc) (append acc s)
+          (collect-fields re (slice s (aget loc 1) (len s)) (append acc (slice s 0 (aget loc 0)))))))
+
+(defn join-groups [m] (concat (aget m 1) "|" (aget m 2)))
+
+(defn find_all [s] (str/join (collect-matches find-all-re s []) "|"))
+(defn find_all_per_call [p s] (str/join (collect-matches p s []) "|"))
+
+(defn capture [s] (join-groups (capture-re s)))
+(defn capture_per_call [p s] (join-groups ((submatch-re p) s)))
+
+(defn replace_all [s] (regexp/replace replace-re s " "))
+(defn replace_all_per_call [p s] (regexp/replace p s " "))
+
+(defn split_fields [s] (str/join (collect-fields split-re s []) "|"))
+(defn split_fields_per_call [p s] (str/join (collect-fields p s []) "|"))
+
+(defn compile_pattern [p] (regexp/compile p))
+`))
+	MustSuccess(t, err)
+	var seq int
+	for _, w := range regexpWorkloads {
+		subBenchmark(t, w.name+"/cached", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				v, err := vm.ApplyByName(w.fn, glisp.MakeArgs(glisp.SexpStr(w.subject)))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, string(v.(glisp.SexpStr)))
+			}
+		})
+		subBenchmark(t, w.name+"/per-call", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				v, err := vm.ApplyByName(w.fn+"_per_call", glisp.MakeArgs(glisp.SexpStr(uniquePattern(w.pattern, seq)), glisp.SexpStr(w.subject)))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, string(v.(glisp.SexpStr)))
+			}
+		})
+		subBenchmark(t, w.name+"/compile", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				_, err := vm.ApplyByName("compile_pattern", glisp.MakeArgs(glisp.SexpStr(uniquePattern(w.pattern, seq))))
+				MustSuccess(t, err)
+			}
+		})
+	}
+}
+
+func benchRegexpOps_goja(t *testing.B) {
+	const SCRIPT = `
+const findAllRe = /\d+/g;
+const captureRe = /(\w+)@(\w+)\.com/;
+const replaceRe = /\s+/g;
+const splitRe = /\s*[,;]\s*/;
+
+function find_all(s) {
+	return s.match(findAllRe).join("|");
+}
+
+function find_all_per_call(p, s) {
+	return s.match(new RegExp(p, "g")).join("|");
+}
+
+function capture(s) {
+	const m = captureRe.exec(s);
+	return m[1] + "|" + m[2];
+}
+
+function capture_per_call(p, s) {
+	const m = new RegExp(p).exec(s);
+	return m[1] + "|" + m[2];
+}
+
+function replace_all(s) {
+	return s.replace(replaceRe, " ");
+}
+
+function replace_all_per_call(p, s) {
+	return s.replace(new RegExp(p, "g"), " ");
+}
+
+function split_fields(s) {
+	return s.split(splitRe).join("|");
+}
+
+function split_fields_per_call(p, s) {
+	return s.split(new RegExp(p)).join("|");
+}
+
+function compile_pattern(p) {
+	return new RegExp(p);
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	compile, ok := goja.AssertFunction(vm.Get("compile_pattern"))
+	MustTrue(t, ok)
+	var seq int
+	for _, w := range regexpWorkloads {
+		cached, ok := goja.AssertFunction(vm.Get(w.fn))
+		MustTrue(t, ok)
+		perCall, ok := goja.AssertFunction(vm.Get(w.fn + "_per_call"))
+		MustTrue(t, ok)
+		subBenchmark(t, w.name+"/cached", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := cached(goja.Undefined(), vm.ToValue(w.subject))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.String())
+			}
+		})
+		subBenchmark(t, w.name+"/per-call", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				res, err := perCall(goja.Undefined(), vm.ToValue(uniquePattern(w.pattern, seq)), vm.ToValue(w.subject))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.String())
+			}
+		})
+		subBenchmark(t, w.name+"/compile", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				_, err := compile(goja.Undefined(), vm.ToValue(uniquePattern(w.pattern, seq)))
+				MustSuccess(t, err)
+			}
+		})
+	}
+}
+
+func benchRegexpOps_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	// go-lua has no pattern matching at all, so compiled Go regexps are
+	// handed to scripts as userdata.
+	checkRegexp := func(l *lua.State) *regexp.Regexp {
+		re, ok := l.ToUserData(1).(*regexp.Regexp)
+		if !ok {
+			lua.ArgumentError(l, 1, "compiled regexp expected")
+		}
+		return re
+	}
+	pushStrings := func(l *lua.State, strs []string) {
+		l.CreateTable(len(strs), 0)
+		for i, s := range strs {
+			l.PushString(s)
+			l.RawSetInt(-2, i+1)
+		}
+	}
+	l.Register("regex_compile", func(l *lua.State) int {
+		re, err := regexp.Compile(lua.CheckString(l, 1))
+		if err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		l.PushUserData(re)
+		return 1
+	})
+	l.Register("regex_find_all", func(l *lua.State) int {
+		re := checkRegexp(l)
+		pushStrings(l, re.FindAllString(lua.CheckString(l, 2), -1))
+		return 1
+	})
+	l.Register("regex_submatch", func(l *lua.State) int {
+		re := checkRegexp(l)
+		pushStrings(l, re.FindStringSubmatch(lua.CheckString(l, 2)))
+		return 1
+	})
+	l.Register("regex_replace", func(l *lua.State) int {
+		re := checkRegexp(l)
+		l.PushString(re.ReplaceAllString(lua.CheckString(l, 2), lua.CheckString(l, 3)))
+		return 1
+	})
+	l.Register("regex_split", func(l *lua.State) int {
+		re := checkRegexp(l)
+		pushStrings(l, re.Split(lua.CheckString(l, 2), -1))
+		return 1
+	})
+	script := `
+local find_all_re = regex_compile("\\d+")
+local capture_re = regex_compile("(\\w+)@(\\w+)\\.com")
+local replace_re = regex_compile("\\s+")
+local split_re = regex_compile("\\s*[,;]\\s*")
+
+function find_all(s)
+  return table.concat(regex_find_all(find_all_re, s), "|")
+end
+
+function find_all_per_call(p, s)
+  return table.concat(regex_find_all(regex_compile(p), s), "|")
+end
+
+function capture(s)
+  local m = regex_submatch(capture_re, s)
+  return m[2] .. "|" .. m[3]
+end
+
+function capture_per_call(p, s)
+  local m = regex_submatch(regex_compile(p), s)
+  return m[2] .. "|" .. m[3]
+end
+
+function replace_all(s)
+  return regex_replace(replace_re, s, " ")
+end
+
+function replace_all_per_call(p, s)
+  return regex_replace(regex_compile(p), s, " ")
+end
+
+function split_fields(s)
+  return table.concat(regex_split(split_re, s), "|")
+end
+
+function split_fields_per_call(p, s)
+  return table.concat(regex_split(regex_compile(p), s), "|")
+end
+
+function compile_pattern(p)
+  return regex_compile(p)
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	var seq int
+	for _, w := range regexpWorkloads {
+		subBenchmark(t, w.name+"/cached", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				l.Global(w.fn)
+				l.PushString(w.subject)
+				err = l.ProtectedCall(1, 1, 0)
+				MustSuccess(t, err)
+				s, ok := l.ToString(-1)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s)
+				l.Pop(1)
+			}
+		})
+		subBenchmark(t, w.name+"/per-call", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				l.Global(w.fn + "_per_call")
+				l.PushString(uniquePattern(w.pattern, seq))
+				l.PushString(w.subject)
+				err = l.ProtectedCall(2, 1, 0)
+				MustSuccess(t, err)
+				s, ok := l.ToString(-1)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s)
+				l.Pop(1)
+			}
+		})
+		subBenchmark(t, w.name+"/compile", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				l.Global("compile_pattern")
+				l.PushString(uniquePattern(w.pattern, seq))
+				err = l.ProtectedCall(1, 1, 0)
+				MustSuccess(t, err)
+				l.Pop(1)
+			}
+		})
+	}
+}
+
+func benchRegexpOps_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	env.ImportRegex()
+	// zygo only ships compile/find/match, so the remaining operations are
+	// registered over its compiled SexpRegexp values.
+	env.AddFunction("regexpFindAll", zygoRegexpFunc(func(re *regexp.Regexp, s string) []string {
+		return re.FindAllString(s, -1)
+	}))
+	env.AddFunction("regexpSubmatch", zygoRegexpFunc(func(re *regexp.Regexp, s string) []string {
+		return re.FindStringSubmatch(s)
+	}))
+	env.AddFunction("regexpSplit", zygoRegexpFunc(func(re *regexp.Regexp, s string) []string {
+		return re.Split(s, -1)
+	}))
+	env.AddFunction("regexpReplace", zygoRegexpFunc(func(re *regexp.Regexp, s string) []string {
+		return []string{re.ReplaceAllString(s, " ")}
+	}))
+	_, err := env.EvalString(`
+(def findAllRe (regexpCompile "\\d+"))
+(def captureRe (regexpCompile "(\\w+)@(\\w+)\\.com"))
+(def replaceRe (regexpCompile "\\s+"))
+(def splitRe (regexpCompile "\\s*[,;]\\s*"))
+
+(defn joinPipe [parts]
+  (def out (aget parts 0))
+  (for [(def i 1) (< i (len parts)) (set i (+ i 1))]
+    (set out (concat out "|" (aget parts i))))
+  out)
+
+(defn find_all [s] (joinPipe (regexpFindAll findAllRe s)))
+(defn find_all_per_call [p s] (joinPipe (regexpFindAll (regexpCompile p) s)))
+
+(defn capture [s] (joinPipe (slice (regexpSubmatch captureRe s) 1 3)))
+(defn capture_per_call [p s] (joinPipe (slice (regexpSubmatch (regexpCompile p) s) 1 3)))
+
+(defn replace_all [s] (aget (regexpReplace replaceRe s) 0))
+(defn replace_all_per_call [p s] (aget (regexpReplace (regexpCompile p) s) 0))
+
+(defn split_fields [s] (joinPipe (regexpSplit splitRe s)))
+(defn split_fields_per_call [p s] (joinPipe (regexpSplit (regexpCompile p) s)))
+
+(defn compile_pattern [p] (regexpCompile p))
+`)
+	MustSuccess(t, err)
+	v, _ := env.FindObject("compile_pattern")
+	compile := v.(*zygo.SexpFunction)
+	var seq int
+	for _, w := range regexpWorkloads {
+		v, _ := env.FindObject(w.fn)
+		cached := v.(*zygo.SexpFunction)
+		v, _ = env.FindObject(w.fn + "_per_call")
+		perCall := v.(*zygo.SexpFunction)
+		subBenchmark(t, w.name+"/cached", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := env.Apply(cached, []zygo.Sexp{&zygo.SexpStr{S: w.subject}})
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.(*zygo.SexpStr).S)
+			}
+		})
+		subBenchmark(t, w.name+"/per-call", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				res, err := env.Apply(perCall, []zygo.Sexp{&zygo.SexpStr{S: uniquePattern(w.pattern, seq)}, &zygo.SexpStr{S: w.subject}})
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.(*zygo.SexpStr).S)
+			}
+		})
+		subBenchmark(t, w.name+"/compile", func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				seq++
+				_, err := env.Apply(compile, []zygo.Sexp{&zygo.SexpStr{S: uniquePattern(w.pattern, seq)}})
+				MustSuccess(t, err)
+			}
+		})
+	}
+}
+
+// zygoRegexpFunc adapts a Go function over a compiled regexp and a subject
+// string into a zygo builtin returning an array of strings.
+func zygoRegexpFunc(f func(re *regexp.Regexp, s string) []string) zygo.ZlispUserFunction {
+	return func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 2 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		re, ok := args[0].(*zygo.SexpRegexp)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("1st argument of %v should be a compiled regular expression", name)
+		}
+		s, ok := args[1].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("2nd argument of %v should be a string", name)
+		}
+		strs := f((*regexp.Regexp)(re), s.S)
+		arr := make([]zygo.Sexp, len(strs))
+		for i, str := range strs {
+			arr[i] = &zygo.SexpStr{S: str}
+		}
+		return &zygo.SexpArray{Val: arr, Env: env}, nil
+	}
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+)
+
+// benchRun is one invocation of the run command, as saved in the results
+// directory and read back by report and compare.
+type benchRun struct {
+	ID        string            `json:"id"`
+	Started   time.Time         `json:"started"`
+	GoVersion string            `json:"go_version"`
+	GOOS      string            `json:"goos"`
+	GOARCH    string            `json:"goarch"`
+	NumCPU    int               `json:"num_cpu"`
+	Modules   map[string]string `json:"modules"`
+	Benchtime string            `json:"benchtime"`
+	Count     int               `json:"count"`
+	Results   []benchResult     `json:"results"`
+	// Latency holds per-call percentiles, from the latency command.
+	Latency []latencyResult `json:"latency,omitempty"`
+}
+
+// benchResult is one benchmark measurement. Name is the go test name
+// without the Benchmark prefix; Workload is the name without the engine,
+// including the sub-benchmark if there is one.
+type benchResult struct {
+	Name        string             `json:"name"`
+	Workload    string             `json:"workload"`
+	Engine      string             `json:"engine"`
+	Procs       int                `json:"procs"`
+	N           int                `json:"n"`
+	NsPerOp     float64            `json:"ns_per_op"`
+	BytesPerOp  int64              `json:"bytes_per_op"`
+	AllocsPerOp int64              `json:"allocs_per_op"`
+	Extra       map[string]float64 `json:"extra,omitempty"`
+	// Runtime holds runtime/metrics deltas per op, see runtimeDeltas.
+	Runtime map[string]float64 `json:"runtime,omitempty"`
+	Failed  bool               `json:"failed,omitempty"`
+}
+
+func newBenchResult(s suiteBenchmark, sub string) benchResult {
+	res := benchResult{Name: s.workload + "_" + s.engine, Workload: s.workload, Engine: s.engine}
+	if sub != "" {
+		res.Name += "/" + sub
+		res.Workload += "/" + sub
+	}
+	return res
+}
+
+func (r *benchResult) setResult(res testing.BenchmarkResult) {
+	r.N = res.N
+	if res.N > 0 {
+		r.NsPerOp = float64(res.T.Nanoseconds()) / float64(res.N)
+	}
+	r.BytesPerOp = res.AllocedBytesPerOp()
+	r.AllocsPerOp = res.AllocsPerOp()
+	if len(res.Extra) > 0 {
+		r.Extra = res.Extra
+	}
+}
+
+// opsPerMs is the throughput the README table reports.
+func (r benchResult) opsPerMs() float64 {
+	return opsPerMs(r.NsPerOp)
+}
+
+// runIDLayout names runs by their start time. Milliseconds keep runs
+// started in the same second, as sweep and scripted loops do, apart.
+const runIDLayout = "20060102-150405.000"
+
+func newBenchRun(benchtime string, count int) *benchRun {
+	started := time.Now()
+	run := &benchRun{
+		ID:        started.Format(runIDLayout),
+		Started:   started,
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		Modules:   make(map[string]string),
+		Benchtime: benchtime,
+		Count:     count,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			run.Modules[dep.Path] = dep.Version
+		}
+	}
+	return run
+}
+
+// saveRun writes run as <dir>/<id>.json and returns the file name. It
+// refuses to overwrite an existing run with the same ID.
+func saveRun(dir string, run *benchRun) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, run.ID+".json")
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	return file, f.Close()
+}
+
+func loadRun(file string) (*benchRun, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var run benchRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &run, nil
+}
+
+// runFilePattern matches the names saveRun gives run files, with or
+// without the milliseconds of runIDLayout, so other JSON files kept next
+// to them are not taken for runs.
+var runFilePattern = regexp.MustCompile(`^\d{8}-\d{6}(\.\d{3})?\.json$`)
+
+// listRuns returns the run files in dir, oldest first.
+func listRuns(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range matches {
+		if runFilePattern.MatchString(filepath.Base(f)) {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// summary is the mean over all repetitions of one benchmark at one
+// GOMAXPROCS value.
+type summary struct {
+	Workload    string  `json:"workload"`
+	Engine      string  `json:"engine"`
+	Procs       int     `json:"procs"`
+	Runs        int     `json:"runs"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	// Runtime is the mean of the runtime/metrics deltas of every run.
+	Runtime map[string]float64 `json:"runtime,omitempty"`
+	Failed  bool               `json:"failed,omitempty"`
+}
+
+func (s summary) key() string {
+	return fmt.Sprintf("%s_%s-%d", s.Workload, s.Engine, s.Procs)
+}
+
+// summarize averages results by workload, engine and GOMAXPROCS, keeping
+// the order in which benchmarks first appear.
+func summarize(results []benchResult) []summary {
+	var out []summary
+	index := make(map[string]int)
+	for _, r := range results {
+		s := summary{Workload: r.Workload, Engine: r.Engine, Procs: r.Procs}
+		i, ok := index[s.key()]
+		if !ok {
+			i = len(out)
+			index[s.key()] = i
+			out = append(out, s)
+		}
+		if r.Failed {
+			out[i].Failed = true
+			continue
+		}
+		n := float64(out[i].Runs)
+		out[i].NsPerOp = (out[i].NsPerOp*n + r.NsPerOp) / (n + 1)
+		out[i].BytesPerOp = int64((float64(out[i].BytesPerOp)*n + float64(r.BytesPerOp)) / (n + 1))
+		out[i].AllocsPerOp = int64((float64(out[i].AllocsPerOp)*n + float64(r.AllocsPerOp)) / (n + 1))
+		for k, v := range r.Runtime {
+			if out[i].Runtime == nil {
+				out[i].Runtime = make(map[string]float64)
+			}
+			out[i].Runtime[k] = (out[i].Runtime[k]*n + v) / (n + 1)
+		}
+		out[i].Runs++
+	}
+	return out
+}
+
+// summarizeStat is summarize with the ns/op of repeated runs combined by
+// stat, "mean" or "median". The other columns stay means.
+func summarizeStat(results []benchResult, stat string) ([]summary, error) {
+	out := summarize(results)
+	switch stat {
+	case "mean":
+		return out, nil
+	case "median":
+	default:
+		return nil, fmt.Errorf("unknown stat %q", stat)
+	}
+	ns := make(map[string][]float64)
+	for _, r := range results {
+		if !r.Failed {
+			k := summary{Workload: r.Workload, Engine: r.Engine, Procs: r.Procs}.key()
+			ns[k] = append(ns[k], r.NsPerOp)
+		}
+	}
+	for i := range out {
+		if v := ns[out[i].key()]; len(v) > 0 {
+			sort.Float64s(v)
+			out[i].NsPerOp = v[len(v)/2]
+		}
+	}
+	return out, nil
+}
+
+// splitMacro separates the summaries of the Macro benchmark, the Benchmarks
+// Game programs, from the micro-benchmarks, since the reports show them in
+// a section of their own.
+func splitMacro(summaries []summary) (micro, macro []summary) {
+	for _, s := range summaries {
+		if strings.SplitN(s.Workload, "/", 2)[0] == "Macro" {
+			macro = append(macro, s)
+		} else {
+			micro = append(micro, s)
+		}
+	}
+	return micro, macro
+}
+
+// engineOrder lists the engines present in summaries, the workload engines
+// first and any others (such as gopherlua) after them.
+func engineOrder(summaries []summary) []string {
+	seen := make(map[string]bool)
+	for _, s := range summaries {
+		seen[s.Engine] = true
+	}
+	var engines []string
+	for _, e := range workloadEngines {
+		if seen[e] {
+			engines = append(engines, e)
+			delete(seen, e)
+		}
+	}
+	var rest []string
+	for e := range seen {
+		rest = append(rest, e)
+	}
+	sort.Strings(rest)
+	return append(engines, rest...)
+}
+
+// textTable is the common shape of everything the CLI prints, so that one
+// writer covers the table, markdown and CSV formats.
+type textTable struct {
+	header []string
+	rows   [][]string
+	// bold marks cells rendered in bold in markdown, by row and column.
+	bold map[[2]int]bool
+}
+
+func (t *textTable) write(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "markdown":
+		fmt.Fprintf(w, "| %s |\n", strings.Join(t.header, " | "))
+		align := make([]string, len(t.header))
+		for i := range align {
+			align[i] = ":---:"
+		}
+		align[0] = ":---"
+		fmt.Fprintf(w, "|%s|\n", strings.Join(align, "|"))
+		for i, row := range t.rows {
+			cells := make([]string, len(row))
+			for j, cell := range row {
+				cells[j] = strings.ReplaceAll(cell, "|", "\\|")
+				if t.bold[[2]int{i, j}] {
+					cells[j] = "**" + cells[j] + "**"
+				}
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(t.header)
+		cw.WriteAll(t.rows)
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatNs(ns float64) string {
+	switch {
+	case ns == 0:
+		return "-"
+	case ns < 10:
+		return strconv.FormatFloat(ns, 'f', 2, 64)
+	case ns < 1000:
+		return strconv.FormatFloat(ns, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(math.Round(ns), 'f', 0, 64)
+}
+
+// resultsTable lists every result of a run, one row per measurement.
+func resultsTable(results []benchResult) *textTable {
+	t := &textTable{header: []string{"Benchmark", "Procs", "N", "ns/op", "op/ms", "B/op", "allocs/op", "Metrics"}}
+	for _, r := range results {
+		if r.Failed {
+			t.rows = append(t.rows, []string{r.Name, strconv.Itoa(r.Procs), "FAIL", "", "", "", "", ""})
+			continue
+		}
+		var metrics []string
+		for unit, v := range r.Extra {
+			metrics = append(metrics, fmt.Sprintf("%s %s", formatNs(v), unit))
+		}
+		sort.Strings(metrics)
+		metrics = append(metrics, runtimeColumns(r.Runtime)...)
+		t.rows = append(t.rows, []string{
+			r.Name,
+			strconv.Itoa(r.Procs),
+			strconv.Itoa(r.N),
+			formatNs(r.NsPerOp),
+			strconv.FormatFloat(r.opsPerMs(), 'f', 0, 64),
+			strconv.FormatInt(r.BytesPerOp, 10),
+			strconv.FormatInt(r.AllocsPerOp, 10),
+			strings.Join(metrics, ", "),
+		})
+	}
+	return t
+}
+
+// enginePivot arranges summaries as the README does: one row per workload
+// (and GOMAXPROCS value, when a run has several) with the mean ns/op of
+// every engine. Missing and failed cells are zero.
+type enginePivot struct {
+	engines []string
+	rows    []string
+	ns      [][]float64
+	failed  map[[2]int]bool
+}
+
+func newEnginePivot(summaries []summary) *enginePivot {
+	p := &enginePivot{engines: engineOrder(summaries), failed: make(map[[2]int]bool)}
+	procs := make(map[int]bool)
+	for _, s := range summaries {
+		procs[s.Procs] = true
+	}
+	type rowKey struct {
+		workload string
+		procs    int
+	}
+	rows := make(map[rowKey]int)
+	for _, s := range summaries {
+		k := rowKey{s.Workload, s.Procs}
+		i, ok := rows[k]
+		if !ok {
+			i = len(p.rows)
+			rows[k] = i
+			name := s.Workload
+			if len(procs) > 1 {
+				name = fmt.Sprintf("%s-%d", name, s.Procs)
+			}
+			p.rows = append(p.rows, name)
+			p.ns = append(p.ns, make([]float64, len(p.engines)))
+		}
+		for j, e := range p.engines {
+			if e != s.Engine {
+				continue
+			}
+			if s.Failed {
+				p.failed[[2]int{i, j}] = true
+			} else {
+				p.ns[i][j] = s.NsPerOp
+			}
+		}
+	}
+	return p
+}
+
+// winner returns the index of the fastest engine of row i, or -1.
+func (p *enginePivot) winner(i int) int {
+	best, winner := math.Inf(1), -1
+	for j, ns := range p.ns[i] {
+		if ns > 0 && ns < best {
+			best, winner = ns, j
+		}
+	}
+	return winner
+}
+
+// opsPerMs converts a pivot cell like benchResult.opsPerMs.
+func opsPerMs(ns float64) float64 {
+	if ns == 0 {
+		return 0
+	}
+	return 1e6 / ns
+}
+
+// engineTable is the README layout: one row per workload, the mean (or
+// the median, see summarizeStat) of every engine in metric (ns/op or
+// op/ms) with the fastest in bold, and the winner.
+func engineTable(summaries []summary, metric string) (*textTable, error) {
+	if metric != "ns/op" && metric != "op/ms" {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	p := newEnginePivot(summaries)
+	t := &textTable{header: []string{"Benchmark"}, bold: make(map[[2]int]bool)}
+	for _, e := range p.engines {
+		t.header = append(t.header, e+" ("+metric+")")
+	}
+	t.header = append(t.header, "Winner")
+	for i, name := range p.rows {
+		row := []string{name}
+		for j, ns := range p.ns[i] {
+			switch {
+			case p.failed[[2]int{i, j}]:
+				row = append(row, "FAIL")
+			case ns == 0:
+				row = append(row, "N/A")
+			case metric == "op/ms":
+				row = append(row, formatNumber(opsPerMs(ns)))
+			default:
+				row = append(row, formatNs(ns))
+			}
+		}
+		if w := p.winner(i); w >= 0 {
+			t.bold[[2]int{i, w + 1}] = true
+			row = append(row, p.engines[w])
+		} else {
+			row = append(row, "N/A")
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// comparison is one benchmark present in both runs passed to compare.
+type comparison struct {
+	Workload string  `json:"workload"`
+	Engine   string  `json:"engine"`
+	Procs    int     `json:"procs"`
+	OldNs    float64 `json:"old_ns_per_op"`
+	NewNs    float64 `json:"new_ns_per_op"`
+	Delta    float64 `json:"delta_percent"`
+}
+
+func compareRuns(old, new *benchRun) []comparison {
+	before := make(map[string]summary)
+	for _, s := range summarize(old.Results) {
+		before[s.key()] = s
+	}
+	var out []comparison
+	for _, s := range summarize(new.Results) {
+		o, ok := before[s.key()]
+		if !ok || o.Failed || s.Failed || o.NsPerOp == 0 {
+			continue
+		}
+		out = append(out, comparison{
+			Workload: s.Workload,
+			Engine:   s.Engine,
+			Procs:    s.Procs,
+			OldNs:    o.NsPerOp,
+			NewNs:    s.NsPerOp,
+			Delta:    (s.NsPerOp - o.NsPerOp) / o.NsPerOp * 100,
+		})
+	}
+	return out
+}
+
+func comparisonTable(comparisons []comparison) *textTable {
+	t := &textTable{header: []string{"Benchmark", "Procs", "old ns/op", "new ns/op", "delta"}}
+	for _, c := range comparisons {
+		t.rows = append(t.rows, []string{
+			c.Workload + "_" + c.Engine,
+			strconv.Itoa(c.Procs),
+			formatNs(c.OldNs),
+			formatNs(c.NewNs),
+			fmt.Sprintf("%+.2f%%", c.Delta),
+		})
+	}
+	return t
+}
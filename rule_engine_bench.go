@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+var ruleCounts = []int{10, 100, 1000}
+
+// ruleEvent is the incoming event every rule set is evaluated against. Each
+// call parses it from JSON in the engine, as a service receiving events
+// would, then runs every rule's predicate and collects the matching IDs.
+const ruleEvent = `{
+  "id": "evt-7f3a91",
+  "type": "order.created",
+  "timestamp": "2026-03-14T09:26:53Z",
+  "device": "mobile",
+  "ip": "203.0.113.7",
+  "user": {"id": 48213, "country": "DE", "tier": "gold", "age": 34, "email": "j.doe@example.com"},
+  "order": {"id": "o-99120", "total": 259.9, "currency": "EUR", "items": 3, "coupon": "SPRING24"},
+  "tags": ["returning", "newsletter"]
+}`
+
+var (
+	ruleCountries  = []string{"DE", "FR", "US", "BR", "JP"}
+	ruleTiers      = []string{"gold", "silver", "bronze"}
+	ruleDevices    = []string{"mobile", "desktop", "tablet"}
+	ruleCurrencies = []string{"EUR", "USD", "BRL"}
+)
+
+// rule is one generated business rule. Rules cycle through four predicate
+// shapes over the event's fields, with parameters varying by index so that
+// roughly a third of any rule set matches ruleEvent.
+type rule struct {
+	id string
+	// kind selects the predicate:
+	//	0: order.total > num && user.country == str
+	//	1: user.tier == str || order.items >= num
+	//	2: device == str && user.age >= num && user.age < num+10
+	//	3: order.currency == str && user.country != str2
+	kind int
+	num  int
+	str  string
+	str2 string
+}
+
+func makeRules(n int) []rule {
+	rules := make([]rule, n)
+	for i := range rules {
+		r := rule{id: fmt.Sprintf("r%d", i), kind: i % 4}
+		switch r.kind {
+		case 0:
+			r.num, r.str = 50*(i%8), ruleCountries[i%5]
+		case 1:
+			r.str, r.num = ruleTiers[i%3], 2+i%6
+		case 2:
+			r.str, r.num = ruleDevices[i%3], 18+i%40
+		case 3:
+			r.str, r.str2 = ruleCurrencies[i%3], ruleCountries[i%5]
+		}
+		rules[i] = r
+	}
+	return rules
+}
+
+// ruleSyntax writes rule predicates in one language: field accesses a
+// path of the event e, and op applies one of >, >=, <, ==, !=, and, or.
+type ruleSyntax struct {
+	field func(path ...string) string
+	op    func(op, a, b string) string
+}
+
+func (r rule) predicate(s ruleSyntax) string {
+	num := fmt.Sprint(r.num)
+	quote := func(v string) string { return `"` + v + `"` }
+	switch r.kind {
+	case 0:
+		return s.op("and",
+			s.op(">", s.field("order", "total"), num),
+			s.op("==", s.field("user", "country"), quote(r.str)))
+	case 1:
+		return s.op("or",
+			s.op("==", s.field("user", "tier"), quote(r.str)),
+			s.op(">=", s.field("order", "items"), num))
+	case 2:
+		return s.op("and",
+			s.op("==", s.field("device"), quote(r.str)),
+			s.op("and",
+				s.op(">=", s.field("user", "age"), num),
+				s.op("<", s.field("user", "age"), fmt.Sprint(r.num+10))))
+	default:
+		return s.op("and",
+			s.op("==", s.field("order", "currency"), quote(r.str)),
+			s.op("!=", s.field("user", "country"), quote(r.str2)))
+	}
+}
+
+// lispSyntax is the syntax of glisp and zygo, which differ in how equality
+// is spelled and in hash keys: glisp parses JSON objects into string keys,
+// zygo into symbols.
+func lispSyntax(eq string, key func(string) string) ruleSyntax {
+	return ruleSyntax{
+		field: func(path ...string) string {
+			expr := "e"
+			for _, k := range path {
+				expr = fmt.Sprintf("(hget %s %s)", expr, key(k))
+			}
+			return expr
+		},
+		op: func(op, a, b string) string {
+			switch op {
+			case "==":
+				op = eq
+			case "!=":
+				return fmt.Sprintf("(not (%s %s %s))", eq, a, b)
+			}
+			return fmt.Sprintf("(%s %s %s)", op, a, b)
+		},
+	}
+}
+
+// infixSyntax is the syntax of JavaScript and Lua, with ops mapping the
+// operators that differ.
+func infixSyntax(ops map[string]string) ruleSyntax {
+	return ruleSyntax{
+		field: func(path ...string) string { return "e." + strings.Join(path, ".") },
+		op: func(op, a, b string) string {
+			if o, ok := ops[op]; ok {
+				op = o
+			}
+			return fmt.Sprintf("(%s %s %s)", a, op, b)
+		},
+	}
+}
+
+// expectedMatches evaluates rules against ruleEvent in Go.
+func expectedMatches(rules []rule) []string {
+	var e struct {
+		Device string `json:"device"`
+		User   struct {
+			Country string `json:"country"`
+			Tier    string `json:"tier"`
+			Age     int    `json:"age"`
+		} `json:"user"`
+		Order struct {
+			Total    float64 `json:"total"`
+			Currency string  `json:"currency"`
+			Items    int     `json:"items"`
+		} `json:"order"`
+	}
+	if err := json.Unmarshal([]byte(ruleEvent), &e); err != nil {
+		panic(err)
+	}
+	var ids []string
+	for _, r := range rules {
+		var match bool
+		switch r.kind {
+		case 0:
+			match = e.Order.Total > float64(r.num) && e.User.Country == r.str
+		case 1:
+			match = e.User.Tier == r.str || e.Order.Items >= r.num
+		case 2:
+			match = e.Device == r.str && e.User.Age >= r.num && e.User.Age < r.num+10
+		case 3:
+			match = e.Order.Currency == r.str && e.User.Country != r.str2
+		}
+		if match {
+			ids = append(ids, r.id)
+		}
+	}
+	return ids
+}
+
+// benchmarkRules runs one sub-benchmark per rule count. load builds an
+// engine holding the rule set and returns a function evaluating ruleEvent,
+// which returns the matching IDs; they are checked in full against
+// expectedMatches once and by count on every call.
+func benchmarkRules(t *testing.B, load func(rules []rule) (func() ([]string, error), error)) {
+	for _, n := range ruleCounts {
+		rules := makeRules(n)
+		want := expectedMatches(rules)
+		eval, err := load(rules)
+		MustSuccess(t, err)
+		got, err := eval()
+		MustSuccess(t, err)
+		MustEqual(t, strings.Join(want, ","), strings.Join(got, ","))
+		subBenchmark(t, fmt.Sprintf("n=%d", n), func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				got, err := eval()
+				MustSuccess(t, err)
+				MustEqualInt64(t, int64(len(want)), int64(len(got)))
+			}
+		})
+	}
+}
+
+func benchRuleEngine_glisp(t *testing.B) {
+	syntax := lispSyntax("=", func(k string) string { return `"` + k + `"` })
+	benchmarkRules(t, func(rules []rule) (func() ([]string, error), error) {
+		var src strings.Builder
+		src.WriteString("(def rules [\n")
+		for _, r := range rules {
+			fmt.Fprintf(&src, "  [%q (fn [e] %s)]\n", r.id, r.predicate(syntax))
+		}
+		src.WriteString(`])
+
+(defn evaluate [json_str]
+  (def e (json/parse json_str))
+  (foldl (fn [r acc] (cond ((aget r 1) e) (append acc (aget r 0)) acc)) [] rules))
+`)
+		vm := glisp.New()
+		ext.ImportAll(vm)
+		if err := vm.SourceStream(bytes.NewBufferString(src.String())); err != nil {
+			return nil, err
+		}
+		args := glisp.MakeArgs(glisp.SexpStr(ruleEvent))
+		return func() ([]string, error) {
+			v, err := vm.ApplyByName("evaluate", args)
+			if err != nil {
+				return nil, err
+			}
+			arr, ok := v.(glisp.SexpArray)
+			if !ok {
+				return nil, fmt.Errorf("evaluate returned %s", v.SexpString())
+			}
+			ids := make([]string, len(arr))
+			for i, id := range arr {
+				ids[i] = string(id.(glisp.SexpStr))
+			}
+			return ids, nil
+		}, nil
+	})
+}
+
+func benchRuleEngine_goja(t *testing.B) {
+	syntax := infixSyntax(map[string]string{"==": "===", "!=": "!==", "and": "&&", "or": "||"})
+	benchmarkRules(t, func(rules []rule) (func() ([]string, error), error) {
+		var src strings.Builder
+		src.WriteString("const rules = [\n")
+		for _, r := range rules {
+			fmt.Fprintf(&src, "  {id: %q, pred: e => %s},\n", r.id, r.predicate(syntax))
+		}
+		src.WriteString(`];
+
+function evaluate(json_str) {
+	const e = JSON.parse(json_str);
+	const out = [];
+	for (const r of rules) {
+		if (r.pred(e)) {
+			out.push(r.id);
+		}
+	}
+	return out;
+}
+`)
+		vm := goja.New()
+		if _, err := vm.RunString(src.String()); err != nil {
+			return nil, err
+		}
+		f, ok := goja.AssertFunction(vm.Get("evaluate"))
+		if !ok {
+			return nil, fmt.Errorf("evaluate is not a function")
+		}
+		event := vm.ToValue(ruleEvent)
+		return func() ([]string, error) {
+			res, err := f(goja.Undefined(), event)
+			if err != nil {
+				return nil, err
+			}
+			var ids []string
+			err = vm.ExportTo(res, &ids)
+			return ids, err
+		}, nil
+	})
+}
+
+// pushLuaJSON pushes a value decoded by encoding/json as nested tables.
+// go-lua has no JSON library, so the event is decoded by a Go function as
+// a host embedding Lua would register one.
+func pushLuaJSON(l *lua.State, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		l.CreateTable(0, len(v))
+		for k, item := range v {
+			pushLuaJSON(l, item)
+			l.SetField(-2, k)
+		}
+	case []interface{}:
+		l.CreateTable(len(v), 0)
+		for i, item := range v {
+			pushLuaJSON(l, item)
+			l.RawSetInt(-2, i+1)
+		}
+	case string:
+		l.PushString(v)
+	case float64:
+		l.PushNumber(v)
+	case bool:
+		l.PushBoolean(v)
+	default:
+		l.PushNil()
+	}
+}
+
+func benchRuleEngine_lua(t *testing.B) {
+	syntax := infixSyntax(map[string]string{"!=": "~="})
+	benchmarkRules(t, func(rules []rule) (func() ([]string, error), error) {
+		var src strings.Builder
+		src.WriteString("local rules = {\n")
+		for _, r := range rules {
+			fmt.Fprintf(&src, "  {id = %q, pred = function(e) return %s end},\n", r.id, r.predicate(syntax))
+		}
+		src.WriteString(`}
+
+function evaluate(json_str)
+  local e = json_decode(json_str)
+  local out = {}
+  for _, r in ipairs(rules) do
+    if r.pred(e) then
+      out[#out + 1] = r.id
+    end
+  end
+  return out
+end
+`)
+		l := lua.NewState()
+		lua.OpenLibraries(l)
+		l.Register("json_decode", func(l *lua.State) int {
+			var v interface{}
+			if err := json.Unmarshal([]byte(lua.CheckString(l, 1)), &v); err != nil {
+				lua.Errorf(l, "%s", err.Error())
+			}
+			pushLuaJSON(l, v)
+			return 1
+		})
+		if err := lua.DoString(l, src.String()); err != nil {
+			return nil, err
+		}
+		return func() ([]string, error) {
+			l.Global("evaluate")
+			l.PushString(ruleEvent)
+			if err := l.ProtectedCall(1, 1, 0); err != nil {
+				l.Pop(1)
+				return nil, err
+			}
+			defer l.Pop(1)
+			ids := make([]string, lua.LengthEx(l, -1))
+			for i := range ids {
+				l.RawGetInt(-1, i+1)
+				ids[i], _ = l.ToString(-1)
+				l.Pop(1)
+			}
+			return ids, nil
+		}, nil
+	})
+}
+
+func benchRuleEngine_zygo(t *testing.B) {
+	syntax := lispSyntax("==", func(k string) string { return "(quote " + k + ")" })
+	benchmarkRules(t, func(rules []rule) (func() ([]string, error), error) {
+		var src strings.Builder
+		src.WriteString("(def rules [\n")
+		for _, r := range rules {
+			fmt.Fprintf(&src, "  [%q (fn [e] %s)]\n", r.id, r.predicate(syntax))
+		}
+		src.WriteString(`])
+
+(defn evaluate [json_str]
+  (def e (parseJSON json_str))
+  (def out [])
+  (for [(def i 0) (< i (len rules)) (set i (+ i 1))]
+    (def r (aget rules i))
+    (cond ((aget r 1) e) (set out (append out (aget r 0))) out))
+  out)
+`)
+		env := zygo.NewZlisp()
+		env.AddFunction("parseJSON",
+			func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+				if len(args) != 1 {
+					return zygo.SexpNull, fmt.Errorf("parseJSON takes one argument")
+				}
+				s, ok := args[0].(*zygo.SexpStr)
+				if !ok {
+					return zygo.SexpNull, fmt.Errorf("parseJSON takes a string")
+				}
+				return zygo.JsonToSexp([]byte(s.S), env)
+			})
+		if _, err := env.EvalString(src.String()); err != nil {
+			return nil, err
+		}
+		v, ok := env.FindObject("evaluate")
+		if !ok {
+			return nil, fmt.Errorf("evaluate not found")
+		}
+		fn := v.(*zygo.SexpFunction)
+		args := []zygo.Sexp{&zygo.SexpStr{S: ruleEvent}}
+		return func() ([]string, error) {
+			res, err := env.Apply(fn, args)
+			if err != nil {
+				return nil, err
+			}
+			arr, ok := res.(*zygo.SexpArray)
+			if !ok {
+				return nil, fmt.Errorf("evaluate returned %s", res.SexpString(nil))
+			}
+			ids := make([]string, len(arr.Val))
+			for i, id := range arr.Val {
+				ids[i] = id.(*zygo.SexpStr).S
+			}
+			return ids, nil
+		}, nil
+	})
+}
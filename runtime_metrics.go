@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"runtime/metrics"
+	"strconv"
+)
+
+// runtimeMetrics are read before and after every benchmark round. Their
+// deltas tell engines apart by the pressure they put on the GC and the
+// scheduler, which hurts neighbouring goroutines more than it shows in
+// ns/op.
+var runtimeMetrics = []string{
+	"/gc/cycles/total:gc-cycles",
+	"/sched/pauses/total/gc:seconds",
+	"/gc/heap/allocs:bytes",
+	"/sched/goroutines:goroutines",
+	"/sched/latencies:seconds",
+}
+
+type runtimeSnapshot []metrics.Sample
+
+func readRuntime() runtimeSnapshot {
+	s := make(runtimeSnapshot, len(runtimeMetrics))
+	for i, name := range runtimeMetrics {
+		s[i].Name = name
+	}
+	metrics.Read(s)
+	return s
+}
+
+// runtimeDeltas turns two snapshots around n ops into the per-op GC and
+// allocation figures, the change in goroutines (non-zero when an engine
+// leaks them) and the scheduler latency of goroutines that became runnable
+// in between. Metrics the runtime does not support are left out.
+func runtimeDeltas(before, after runtimeSnapshot, n int) map[string]float64 {
+	if n <= 0 {
+		return nil
+	}
+	out := make(map[string]float64)
+	perOp := float64(n)
+	for i := range before {
+		b, a := before[i].Value, after[i].Value
+		if b.Kind() == metrics.KindBad || a.Kind() != b.Kind() {
+			continue
+		}
+		switch before[i].Name {
+		case "/gc/cycles/total:gc-cycles":
+			out["gc-cycles/op"] = float64(a.Uint64()-b.Uint64()) / perOp
+		case "/gc/heap/allocs:bytes":
+			out["heap-alloc-B/op"] = float64(a.Uint64()-b.Uint64()) / perOp
+		case "/sched/goroutines:goroutines":
+			out["goroutines"] = float64(a.Uint64()) - float64(b.Uint64())
+		case "/sched/pauses/total/gc:seconds":
+			_, sum := histogramDelta(b.Float64Histogram(), a.Float64Histogram())
+			out["gc-pause-ns/op"] = sum * 1e9 / perOp
+		case "/sched/latencies:seconds":
+			counts, sum := histogramDelta(b.Float64Histogram(), a.Float64Histogram())
+			if counts > 0 {
+				out["sched-latency-ns"] = sum * 1e9 / float64(counts)
+			}
+		}
+	}
+	return out
+}
+
+// histogramDelta returns the number of values recorded into a runtime
+// histogram between before and after, and their approximate sum, taking
+// every value at the middle of its bucket.
+func histogramDelta(before, after *metrics.Float64Histogram) (count uint64, sum float64) {
+	for i := range after.Counts {
+		c := after.Counts[i] - before.Counts[i]
+		if c == 0 {
+			continue
+		}
+		lo, hi := after.Buckets[i], after.Buckets[i+1]
+		if math.IsInf(lo, -1) {
+			lo = 0
+		}
+		if math.IsInf(hi, 1) {
+			hi = lo
+		}
+		count += c
+		sum += float64(c) * (lo + hi) / 2
+	}
+	return count, sum
+}
+
+// runtimeKeys orders the runtimeDeltas keys for display.
+var runtimeKeys = []string{"gc-cycles/op", "gc-pause-ns/op", "heap-alloc-B/op", "goroutines", "sched-latency-ns"}
+
+// runtimeColumns formats the runtimeDeltas of a result for the Metrics
+// column. A goroutine delta is only shown when it is not zero.
+func runtimeColumns(rt map[string]float64) []string {
+	var out []string
+	for _, k := range runtimeKeys {
+		v, ok := rt[k]
+		if !ok || k == "goroutines" && v == 0 {
+			continue
+		}
+		s := strconv.FormatFloat(v, 'g', 3, 64)
+		if math.Abs(v) >= 1000 {
+			s = strconv.FormatFloat(v, 'f', 0, 64)
+		}
+		out = append(out, s+" "+k)
+	}
+	return out
+}
@@ -0,0 +1,291 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// The sandbox safety test runs scripts that allocate without bound and
+// records how each engine copes. It is off by default because it spends
+// minutes pushing the process towards its memory limit:
+//
+//	go test -run Sandbox -sandbox -sandbox.report SANDBOX.md
+//
+// Every bomb runs in a child copy of the test binary so a crash only takes
+// down that child. The child sets sandboxMemoryLimit with
+// debug.SetMemoryLimit and caps its data segment at sandboxHardCap with
+// RLIMIT_DATA, so the kernel refuses the allocation that would pass the
+// cap and the engine and the Go runtime decide what happens next. The
+// address space is left alone: the Go runtime reserves more than a
+// gigabyte of it before main runs.
+var (
+	sandbox       = flag.Bool("sandbox", false, "run allocation-bomb scripts under a memory limit")
+	sandboxReport = flag.String("sandbox.report", "", "write the sandbox safety report as markdown to this file")
+)
+
+const (
+	sandboxMemoryLimit = 256 << 20
+	sandboxHardCap     = 768 << 20
+	sandboxTimeout     = 5 * time.Minute
+	sandboxBombEnv     = "GLISP_BENCH_SANDBOX_BOMB"
+	sandboxResultTag   = "SANDBOX-RESULT "
+)
+
+// Outcomes of a bomb run, from best to worst for a host process. The first
+// three are reported by the child itself, the rest by the parent when the
+// child dies without a result line.
+const (
+	outcomeError    = "clean error"
+	outcomePanic    = "recovered panic"
+	outcomeReturned = "returned"
+	outcomeTimeout  = "timeout"
+	outcomeOOM      = "out of memory"
+	outcomeKilled   = "killed"
+	outcomeCrashed  = "crashed"
+)
+
+// bomb is one allocation bomb; run only returns once the engine gives up.
+type bomb struct {
+	engine string
+	kind   string
+	run    func() error
+}
+
+type bombResult struct {
+	Engine  string        `json:"engine"`
+	Bomb    string        `json:"bomb"`
+	Outcome string        `json:"outcome"`
+	PeakRSS uint64        `json:"peak_rss"`
+	Elapsed time.Duration `json:"elapsed"`
+	Detail  string        `json:"detail"`
+}
+
+func bombs() []bomb {
+	return []bomb{
+		{"glisp", "string", glispBomb(`(defn grow [s] (grow (concat s s))) (defn bomb [] (grow "x"))`)},
+		{"glisp", "hash", glispBomb(`(defn grow [h n] (hset! h n (string n)) (grow h (+ n 1))) (defn bomb [] (grow (hash) 0))`)},
+		{"glisp", "list", glispBomb(`(defn grow [l n] (grow (cons n l) (+ n 1))) (defn bomb [] (grow '() 0))`)},
+		{"goja", "string", gojaBomb(`let s = "x"; for (;;) { s += s; }`)},
+		{"goja", "hash", gojaBomb(`const m = {}; for (let i = 0; ; i++) { m["k" + i] = String(i); }`)},
+		{"goja", "list", gojaBomb(`const a = []; for (let i = 0; ; i++) { a.push(i); }`)},
+		{"lua", "string", luaBomb(`local s = "x" while true do s = s .. s end`)},
+		{"lua", "hash", luaBomb(`local m = {} local i = 0 while true do m["k" .. i] = tostring(i) i = i + 1 end`)},
+		{"lua", "list", luaBomb(`local a = {} local i = 0 while true do a[#a + 1] = i i = i + 1 end`)},
+		{"zygo", "string", zygoBomb(`(def s "x") (for [(def i 0) true (set i (+ i 1))] (set s (concat s s)))`)},
+		{"zygo", "hash", zygoBomb(`(def m (hash)) (for [(def i 0) true (set i (+ i 1))] (hset m i (sprintf "%d" i)))`)},
+		{"zygo", "list", zygoBomb(`(def l (list)) (for [(def i 0) true (set i (+ i 1))] (set l (cons i l)))`)},
+	}
+}
+
+func glispBomb(script string) func() error {
+	return func() error {
+		vm := glisp.New()
+		ext.ImportCoreUtils(vm)
+		if err := vm.SourceStream(bytes.NewBufferString(script)); err != nil {
+			return err
+		}
+		_, err := vm.ApplyByName("bomb", glisp.MakeArgs())
+		return err
+	}
+}
+
+func gojaBomb(script string) func() error {
+	return func() error {
+		_, err := goja.New().RunString(script)
+		return err
+	}
+}
+
+func luaBomb(script string) func() error {
+	return func() error {
+		l := lua.NewState()
+		lua.OpenLibraries(l)
+		return lua.DoString(l, script)
+	}
+}
+
+func zygoBomb(script string) func() error {
+	return func() error {
+		_, err := zygo.NewZlisp().EvalString(script)
+		return err
+	}
+}
+
+func TestSandboxSafety(t *testing.T) {
+	if name := os.Getenv(sandboxBombEnv); name != "" {
+		runBomb(t, name)
+		return
+	}
+	if !*sandbox {
+		t.Skip("sandbox safety test is enabled with -sandbox")
+	}
+	var results []bombResult
+	for _, b := range bombs() {
+		res := spawnBomb(t, b)
+		t.Logf("%s/%s: %s, peak RSS %d MiB, %v %s", res.Engine, res.Bomb, res.Outcome, res.PeakRSS>>20, res.Elapsed.Round(time.Millisecond), res.Detail)
+		results = append(results, res)
+	}
+	if *sandboxReport != "" {
+		err := os.WriteFile(*sandboxReport, []byte(sandboxMarkdown(results)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// spawnBomb runs b in a child test binary and reads back its result line.
+// The peak RSS and the elapsed time come from the parent, so they are known
+// however the child ends. A child that dies without a result line is
+// classified from its exit status and the last line it wrote to stderr.
+func spawnBomb(t *testing.T, b bomb) bombResult {
+	ctx, cancel := context.WithTimeout(context.Background(), sandboxTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestSandboxSafety$")
+	cmd.Env = append(os.Environ(), sandboxBombEnv+"="+b.engine+"/"+b.kind)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	start := time.Now()
+	err := cmd.Run()
+	res := bombResult{Engine: b.engine, Bomb: b.kind}
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), sandboxResultTag); ok {
+			if err := json.Unmarshal([]byte(line), &res); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	res.Elapsed = time.Since(start)
+	if cmd.ProcessState != nil {
+		// Maxrss is in KiB on Linux.
+		res.PeakRSS = uint64(cmd.ProcessState.SysUsage().(*syscall.Rusage).Maxrss) << 10
+	}
+	if res.Outcome != "" {
+		return res
+	}
+	detail := crashLine(stderr.String())
+	status, _ := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.Outcome = outcomeTimeout
+	case status.Signaled():
+		res.Outcome, res.Detail = outcomeKilled, status.Signal().String()
+	case strings.Contains(detail, "out of memory"), strings.Contains(detail, "cannot allocate memory"):
+		res.Outcome, res.Detail = outcomeOOM, detail
+	default:
+		res.Outcome, res.Detail = outcomeCrashed, detail
+		if res.Detail == "" && err != nil {
+			res.Detail = err.Error()
+		}
+	}
+	return res
+}
+
+// runBomb is the child side of spawnBomb. It never returns: the result is
+// printed as one JSON line and the process exits, unless the runtime dies
+// first.
+func runBomb(t *testing.T, name string) {
+	var b *bomb
+	for _, candidate := range bombs() {
+		if candidate.engine+"/"+candidate.kind == name {
+			b = &candidate
+		}
+	}
+	if b == nil {
+		t.Fatalf("unknown bomb %s", name)
+	}
+	limit := syscall.Rlimit{Cur: sandboxHardCap, Max: sandboxHardCap}
+	if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &limit); err != nil {
+		t.Fatal(err)
+	}
+	debug.SetMemoryLimit(sandboxMemoryLimit)
+	res := bombResult{Engine: b.engine, Bomb: b.kind, Outcome: outcomeError}
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				res.Outcome, err = outcomePanic, fmt.Errorf("%v", r)
+			}
+		}()
+		return b.run()
+	}()
+	if err == nil {
+		res.Outcome, err = outcomeReturned, errors.New("script returned without error")
+	}
+	res.Detail = lastLine(err.Error())
+	line, _ := json.Marshal(res)
+	fmt.Println(sandboxResultTag + string(line))
+	// testing rejects os.Exit(0) while a test runs; the parent only reads
+	// the result line, so any status will do.
+	os.Exit(2)
+}
+
+func sandboxMarkdown(results []bombResult) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Memory limit %d MiB, RLIMIT_DATA %d MiB.\n\n", sandboxMemoryLimit>>20, sandboxHardCap>>20)
+	buf.WriteString("| Engine | Bomb | Outcome | Survives | Clean error | Peak RSS (MiB) | Time | Detail |\n")
+	buf.WriteString("|:--- |:--- |:--- |:---:|:---:|:---:|:---:|:--- |\n")
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	// The process survives whenever the child got to report back; only a
+	// timeout leaves open what the engine would have done in the end.
+	survives := func(outcome string) string {
+		switch outcome {
+		case outcomeError, outcomePanic, outcomeReturned:
+			return "yes"
+		case outcomeTimeout:
+			return "n/a"
+		}
+		return "no"
+	}
+	for _, r := range results {
+		fmt.Fprintf(&buf, "| %s | %s | %s | %s | %s | %d | %v | %s |\n",
+			r.Engine, r.Bomb, r.Outcome,
+			survives(r.Outcome),
+			yesNo(r.Outcome == outcomeError),
+			r.PeakRSS>>20, r.Elapsed.Round(time.Millisecond),
+			strings.ReplaceAll(r.Detail, "|", "\\|"))
+	}
+	return buf.String()
+}
+
+// crashLine picks the line of a dead child's stderr that says why it died:
+// the runtime's fatal error or panic message, which the goroutine dump
+// follows.
+func crashLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, "fatal error:") || strings.HasPrefix(line, "panic:") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return lastLine(s)
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
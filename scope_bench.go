@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// variableScopes are the sub-benchmarks of VariableScope. Every script
+// defines <scope>_access(n), which sums i*k + b for i below n into acc,
+// with k, b and acc held as globals, as locals of the function, or as the
+// captured variables of a closure. Each round reads k and b and writes acc
+// in the scope under test.
+var variableScopes = []string{"global", "local", "closure"}
+
+const scopeIterations = 1000
+
+func expectedScopeSum() int64 {
+	var acc int64
+	for i := int64(0); i < scopeIterations; i++ {
+		acc = acc + i*3 + 7
+	}
+	return acc
+}
+
+func benchVariableScope_glisp(t *testing.B) {
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	// glisp iterates by recursion and a fn cannot call itself by name, so
+	// every variant recurses through a step fn passed itself as self. In
+	// global it reads k and b and writes acc as globals, and in closure as
+	// let bindings captured once by make-access. In local they are
+	// parameters of step, so every round reads and rebinds call-local
+	// bindings and nothing is captured. def inside a function, as in
+	// JSONParseAndModify, binds a local of that call rather than writing the
+	// global, so acc is assigned with set!.
+	err := vm.SourceStream(bytes.NewBufferString(`
+(def k 3)
+(def b 7)
+(def acc 0)
+
+(defn global_access [n]
+  (set! acc 0)
+  (let [step (fn [self i n]
+               (when (< i n)
+                 (set! acc (+ acc (* i k) b))
+                 (self self (+ i 1) n)))]
+    (step step 0 n))
+  acc)
+
+(defn local_access [n]
+  (let [step (fn [self i n k b acc]
+               (cond (< i n) (self self (+ i 1) n k b (+ acc (* i k) b)) acc))]
+    (step step 0 n 3 7 0)))
+
+(defn make-access []
+  (let* [k 3
+         b 7
+         acc 0
+         step (fn [self i n]
+                (when (< i n)
+                  (set! acc (+ acc (* i k) b))
+                  (self self (+ i 1) n)))]
+    (fn [n]
+      (set! acc 0)
+      (step step 0 n)
+      acc)))
+
+(def closure_access (make-access))
+`))
+	MustSuccess(t, err)
+	want := expectedScopeSum()
+	for _, scope := range variableScopes {
+		subBenchmark(t, scope, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				v, err := vm.ApplyByName(scope+"_access", glisp.MakeArgs(glisp.NewSexpInt64(scopeIterations)))
+				MustSuccess(t, err)
+				n, ok := v.(glisp.SexpInt)
+				MustTrue(t, ok)
+				MustEqualInt64(t, want, n.ToInt64())
+			}
+		})
+	}
+}
+
+func benchVariableScope_goja(t *testing.B) {
+	// var at the top level makes properties of the global object, unlike
+	// let and const, which live in the script scope.
+	const SCRIPT = `
+var k = 3, b = 7, acc = 0;
+
+function global_access(n) {
+	acc = 0;
+	for (let i = 0; i < n; i++) {
+		acc = acc + i * k + b;
+	}
+	return acc;
+}
+
+function local_access(n) {
+	let k = 3, b = 7, acc = 0;
+	for (let i = 0; i < n; i++) {
+		acc = acc + i * k + b;
+	}
+	return acc;
+}
+
+const closure_access = (function () {
+	let k = 3, b = 7, acc = 0;
+	return function (n) {
+		acc = 0;
+		for (let i = 0; i < n; i++) {
+			acc = acc + i * k + b;
+		}
+		return acc;
+	};
+})();
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	want := expectedScopeSum()
+	n := vm.ToValue(scopeIterations)
+	for _, scope := range variableScopes {
+		f, ok := goja.AssertFunction(vm.Get(scope + "_access"))
+		MustTrue(t, ok)
+		subBenchmark(t, scope, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := f(goja.Undefined(), n)
+				MustSuccess(t, err)
+				MustEqualInt64(t, want, res.ToInteger())
+			}
+		})
+	}
+}
+
+func benchVariableScope_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	err := lua.DoString(l, `
+k, b, acc = 3, 7, 0
+
+function global_access(n)
+  acc = 0
+  for i = 0, n - 1 do
+    acc = acc + i * k + b
+  end
+  return acc
+end
+
+function local_access(n)
+  local k, b, acc = 3, 7, 0
+  for i = 0, n - 1 do
+    acc = acc + i * k + b
+  end
+  return acc
+end
+
+closure_access = (function()
+  local k, b, acc = 3, 7, 0
+  return function(n)
+    acc = 0
+    for i = 0, n - 1 do
+      acc = acc + i * k + b
+    end
+    return acc
+  end
+end)()
+`)
+	MustSuccess(t, err)
+	want := expectedScopeSum()
+	for _, scope := range variableScopes {
+		subBenchmark(t, scope, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				l.Global(scope + "_access")
+				l.PushInteger(scopeIterations)
+				err := l.ProtectedCall(1, 1, 0)
+				MustSuccess(t, err)
+				n, ok := l.ToInteger(-1)
+				MustTrue(t, ok)
+				MustEqualInt64(t, want, int64(n))
+				l.Pop(1)
+			}
+		})
+	}
+}
+
+func benchVariableScope_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	_, err := env.EvalString(`
+(def k 3)
+(def b 7)
+(def acc 0)
+
+(defn global_access [n]
+  (set acc 0)
+  (for [(def i 0) (< i n) (set i (+ i 1))]
+    (set acc (+ acc (* i k) b)))
+  acc)
+
+(defn local_access [n]
+  (let [k 3 b 7 acc 0]
+    (for [(def i 0) (< i n) (set i (+ i 1))]
+      (set acc (+ acc (* i k) b)))
+    acc))
+
+(def closure_access
+  (let [k 3 b 7 acc 0]
+    (fn [n]
+      (set acc 0)
+      (for [(def i 0) (< i n) (set i (+ i 1))]
+        (set acc (+ acc (* i k) b)))
+      acc)))
+`)
+	MustSuccess(t, err)
+	want := expectedScopeSum()
+	args := []zygo.Sexp{&zygo.SexpInt{Val: scopeIterations}}
+	for _, scope := range variableScopes {
+		v, ok := env.FindObject(scope + "_access")
+		MustTrue(t, ok)
+		fn := v.(*zygo.SexpFunction)
+		subBenchmark(t, scope, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := env.Apply(fn, args)
+				MustSuccess(t, err)
+				n, ok := res.(*zygo.SexpInt)
+				if !ok {
+					t.Fatalf("%s_access returned %s", scope, res.SexpString(nil))
+				}
+				MustEqualInt64(t, want, n.Val)
+			}
+		})
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+var seqSizes = []int{100, 1000, 10000}
+
+// seqOp is one sequence operation. Every engine's script keeps the values and
+// indexes handed to setup and defines fn returning expect. Ops that produce a
+// sequence return its probe, first*100000 + last, so results stay comparable.
+// After set_full(true) the probe is instead the checksum of every element,
+// sum((i+1)*s[i]), which each op is checked against once before timing.
+type seqOp struct {
+	name     string
+	fn       string
+	expect   int64
+	checksum int64
+}
+
+// seqData returns a permutation of 0..n-1 and 100 pseudo-random indexes into it.
+func seqData(n int) (values, idx []int64) {
+	values = make([]int64, n)
+	for i := range values {
+		values[i] = int64((i*37 + 11) % n)
+	}
+	idx = make([]int64, 100)
+	for j := range idx {
+		idx[j] = int64((j*53 + 7) % n)
+	}
+	return
+}
+
+func seqOps(values, idx []int64) []seqOp {
+	n := len(values)
+	probe := func(s []int64) int64 { return s[0]*100000 + s[len(s)-1] }
+	checksum := func(s []int64) (c int64) {
+		for i, v := range s {
+			c += int64(i+1) * v
+		}
+		return
+	}
+	var sum int64
+	for _, i := range idx {
+		sum += values[i]
+	}
+	var found int64
+	for j := int64(0); j < 100; j++ {
+		for _, v := range values {
+			if v == j*2 {
+				found++
+				break
+			}
+		}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+	reversed := make([]int64, n)
+	for i, v := range values {
+		reversed[n-1-i] = v
+	}
+	return []seqOp{
+		{"append", "append_all", probe(values), checksum(values)},
+		{"index", "index_sum", sum, sum},
+		{"slice", "slice_probe", probe(values[n/4 : 3*n/4]), checksum(values[n/4 : 3*n/4])},
+		{"reverse", "reverse_probe", probe(reversed), checksum(reversed)},
+		{"sort", "sort_probe", probe(sorted), checksum(sorted)},
+		{"contains", "contains_count", found, found},
+	}
+}
+
+func benchSequence_glisp(t *testing.B) {
+	scripts := []struct {
+		name   string
+		script string
+	}{
+		{"array", `
+(def data [])
+(def idx [])
+
+(def full false)
+
+(defn setup [values indexes]
+  (set! data values)
+  (set! idx indexes))
+
+(defn set_full [on] (set! full on))
+
+(defn checksum-from [s i acc]
+  (cond (= i (len s)) acc
+        (checksum-from s (+ i 1) (+ acc (* (+ i 1) (aget s i))))))
+
+(defn probe [s]
+  (cond full (checksum-from s 0 0)
+        (+ (* (aget s 0) 100000) (aget s (- (len s) 1)))))
+
+(defn append-from [acc i n]
+  (cond (= i n) acc
+        (append-from (append acc (aget data i)) (+ i 1) n)))
+
+(defn append_all [] (probe (append-from [] 0 (len data))))
+
+(defn sum-at [i acc]
+  (cond (= i (len idx)) acc
+        (sum-at (+ i 1) (+ acc (aget data (aget idx i))))))
+
+(defn index_sum [] (sum-at 0 0))
+
+(defn slice_probe []
+  (let [n (len data)]
+    (probe (slice data (/ n 4) (/ (* 3 n) 4)))))
+
+(defn reverse_probe [] (probe (reverse data)))
+
+(defn sort_probe [] (probe (sort #(> %1 %2) (concat [] data))))
+
+(defn count-members [j acc]
+  (cond (= j 100) acc
+        (count-members (+ j 1) (cond (exist? data (* j 2)) (+ acc 1) acc))))
+
+(defn contains_count [] (count-members 0 0))
+`},
+		{"list", `
+(def data '())
+(def idx [])
+
+(def full false)
+
+(defn setup [values indexes]
+  (set! data (array-to-list values))
+  (set! idx indexes))
+
+(defn set_full [on] (set! full on))
+
+(defn checksum-from [l i acc]
+  (cond (nil? l) acc
+        (checksum-from (cdr l) (+ i 1) (+ acc (* i (car l))))))
+
+(defn probe [l]
+  (cond full (checksum-from l 1 0)
+        (+ (* (car l) 100000) (nth (- (len l) 1) l))))
+
+(defn cons-all [l acc]
+  (cond (nil? l) acc
+        (cons-all (cdr l) (cons (car l) acc))))
+
+(defn append_all [] (probe (reverse (cons-all data '()))))
+
+(defn sum-at [i acc]
+  (cond (= i (len idx)) acc
+        (sum-at (+ i 1) (+ acc (nth (aget idx i) data)))))
+
+(defn index_sum [] (sum-at 0 0))
+
+(defn slice_probe []
+  (let [n (len data)]
+    (probe (->> (stream data)
+                (drop (/ n 4))
+                (take (/ n 2))
+                (realize)))))
+
+(defn reverse_probe [] (probe (reverse data)))
+
+(defn sort_probe [] (probe (sort #(> %1 %2) data)))
+
+(defn count-members [j acc]
+  (cond (= j 100) acc
+        (count-members (+ j 1) (cond (exist? data (* j 2)) (+ acc 1) acc))))
+
+(defn contains_count [] (count-members 0 0))
+`},
+	}
+	for _, s := range scripts {
+		vm := glisp.New()
+		ext.ImportCoreUtils(vm)
+		err := vm.SourceStream(bytes.NewBufferString(s.script))
+		MustSuccess(t, err)
+		for _, n := range seqSizes {
+			values, idx := seqData(n)
+			_, err := vm.ApplyByName("setup", glisp.MakeArgs(glispInts(values), glispInts(idx)))
+			MustSuccess(t, err)
+			for _, op := range seqOps(values, idx) {
+				_, err = vm.ApplyByName("set_full", glisp.MakeArgs(glisp.SexpBool(true)))
+				MustSuccess(t, err)
+				v, err := vm.ApplyByName(op.fn, glisp.MakeArgs())
+				MustSuccess(t, err)
+				MustEqualInt64(t, op.checksum, v.(glisp.SexpInt).ToInt64())
+				_, err = vm.ApplyByName("set_full", glisp.MakeArgs(glisp.SexpBool(false)))
+				MustSuccess(t, err)
+				subBenchmark(t, fmt.Sprintf("%s/n=%d/%s", s.name, n, op.name), func(t *testing.B) {
+					for i := 0; i < t.N; i++ {
+						v, err := vm.ApplyByName(op.fn, glisp.MakeArgs())
+						MustSuccess(t, err)
+						num, ok := v.(glisp.SexpInt)
+						MustTrue(t, ok)
+						MustEqualInt64(t, op.expect, num.ToInt64())
+					}
+				})
+			}
+		}
+	}
+}
+
+func benchSequence_goja(t *testing.B) {
+	const SCRIPT = `
+let data = [], idx = [], full = false;
+
+function setup(values, indexes) {
+	data = values;
+	idx = indexes;
+}
+
+function set_full(on) {
+	full = on;
+}
+
+function probe(s) {
+	if (full) {
+		let c = 0;
+		for (let i = 0; i < s.length; i++) {
+			c += (i + 1) * s[i];
+		}
+		return c;
+	}
+	return s[0] * 100000 + s[s.length - 1];
+}
+
+function append_all() {
+	const out = [];
+	for (let i = 0; i < data.length; i++) {
+		out.push(data[i]);
+	}
+	return probe(out);
+}
+
+function index_sum() {
+	let sum = 0;
+	for (let i = 0; i < idx.length; i++) {
+		sum += data[idx[i]];
+	}
+	return sum;
+}
+
+function slice_probe() {
+	const n = data.length;
+	return probe(data.slice(n / 4, 3 * n / 4));
+}
+
+function reverse_probe() {
+	return probe(data.slice().reverse());
+}
+
+function sort_probe() {
+	return probe(data.slice().sort((a, b) => b - a));
+}
+
+function contains_count() {
+	let c = 0;
+	for (let j = 0; j < 100; j++) {
+		if (data.includes(j * 2)) c++;
+	}
+	return c;
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	setup, ok := goja.AssertFunction(vm.Get("setup"))
+	MustTrue(t, ok)
+	setFull, ok := goja.AssertFunction(vm.Get("set_full"))
+	MustTrue(t, ok)
+	toArray := func(nums []int64) *goja.Object {
+		items := make([]interface{}, len(nums))
+		for i, v := range nums {
+			items[i] = v
+		}
+		return vm.NewArray(items...)
+	}
+	for _, n := range seqSizes {
+		values, idx := seqData(n)
+		_, err := setup(goja.Undefined(), toArray(values), toArray(idx))
+		MustSuccess(t, err)
+		for _, op := range seqOps(values, idx) {
+			f, ok := goja.AssertFunction(vm.Get(op.fn))
+			MustTrue(t, ok)
+			_, err = setFull(goja.Undefined(), vm.ToValue(true))
+			MustSuccess(t, err)
+			res, err := f(goja.Undefined())
+			MustSuccess(t, err)
+			MustEqualInt64(t, op.checksum, res.ToInteger())
+			_, err = setFull(goja.Undefined(), vm.ToValue(false))
+			MustSuccess(t, err)
+			subBenchmark(t, fmt.Sprintf("n=%d/%s", n, op.name), func(t *testing.B) {
+				for i := 0; i < t.N; i++ {
+					res, err := f(goja.Undefined())
+					MustSuccess(t, err)
+					MustEqualInt64(t, op.expect, res.ToInteger())
+				}
+			})
+		}
+	}
+}
+
+func benchSequence_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	script := `
+local data, idx, full = {}, {}, false
+
+function setup(values, indexes)
+  data = values
+  idx = indexes
+end
+
+function set_full(on)
+  full = on
+end
+
+local function probe(s)
+  if full then
+    local c = 0
+    for i = 1, #s do
+      c = c + i * s[i]
+    end
+    return c
+  end
+  return s[1] * 100000 + s[#s]
+end
+
+function append_all()
+  local out = {}
+  for i = 1, #data do
+    out[#out + 1] = data[i]
+  end
+  return probe(out)
+end
+
+function index_sum()
+  local sum = 0
+  for i = 1, #idx do
+    sum = sum + data[idx[i] + 1]
+  end
+  return sum
+end
+
+function slice_probe()
+  local n = #data
+  local out = {}
+  for i = n / 4 + 1, 3 * n / 4 do
+    out[#out + 1] = data[i]
+  end
+  return probe(out)
+end
+
+function reverse_probe()
+  local out = {}
+  for i = #data, 1, -1 do
+    out[#out + 1] = data[i]
+  end
+  return probe(out)
+end
+
+function sort_probe()
+  local out = {}
+  for i = 1, #data do
+    out[i] = data[i]
+  end
+  table.sort(out, function(a, b) return a > b end)
+  return probe(out)
+end
+
+local function contains(t, x)
+  for i = 1, #t do
+    if t[i] == x then
+      return true
+    end
+  end
+  return false
+end
+
+function contains_count()
+  local c = 0
+  for j = 0, 99 do
+    if contains(data, j * 2) then
+      c = c + 1
+    end
+  end
+  return c
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	pushInts := func(nums []int64) {
+		l.CreateTable(len(nums), 0)
+		for i, v := range nums {
+			l.PushInteger(int(v))
+			l.RawSetInt(-2, i+1)
+		}
+	}
+	for _, n := range seqSizes {
+		values, idx := seqData(n)
+		l.Global("setup")
+		pushInts(values)
+		pushInts(idx)
+		err = l.ProtectedCall(2, 0, 0)
+		MustSuccess(t, err)
+		setFull := func(on bool) {
+			l.Global("set_full")
+			l.PushBoolean(on)
+			MustSuccess(t, l.ProtectedCall(1, 0, 0))
+		}
+		for _, op := range seqOps(values, idx) {
+			setFull(true)
+			l.Global(op.fn)
+			MustSuccess(t, l.ProtectedCall(0, 1, 0))
+			v, _ := l.ToInteger(-1)
+			MustEqualInt64(t, op.checksum, int64(v))
+			l.Pop(1)
+			setFull(false)
+			subBenchmark(t, fmt.Sprintf("n=%d/%s", n, op.name), func(t *testing.B) {
+				for i := 0; i < t.N; i++ {
+					l.Global(op.fn)
+					err = l.ProtectedCall(0, 1, 0)
+					MustSuccess(t, err)
+					v, _ := l.ToInteger(-1)
+					MustEqualInt64(t, op.expect, int64(v))
+					l.Pop(1)
+				}
+			})
+		}
+	}
+}
+
+func benchSequence_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	// zygo has no sort builtin; sortBy runs Go's sort with a script comparator,
+	// which is also how glisp's (sort f coll) works.
+	env.AddFunction("sortBy", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 2 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		arr, ok := args[0].(*zygo.SexpArray)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("1st argument of %v should be an array", name)
+		}
+		less, ok := args[1].(*zygo.SexpFunction)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("2nd argument of %v should be a function", name)
+		}
+		out := append([]zygo.Sexp(nil), arr.Val...)
+		var err error
+		sort.SliceStable(out, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+			var res zygo.Sexp
+			res, err = env.Apply(less, []zygo.Sexp{out[i], out[j]})
+			b, _ := res.(*zygo.SexpBool)
+			return b != nil && b.Val
+		})
+		if err != nil {
+			return zygo.SexpNull, err
+		}
+		return &zygo.SexpArray{Val: out, Env: env}, nil
+	})
+	_, err := env.EvalString(`
+(def data (array))
+(def idx (array))
+(def full false)
+
+(defn setup [values indexes]
+  (set data values)
+  (set idx indexes))
+
+(defn set_full [on] (set full on))
+
+(defn checksum [s]
+  (def c 0)
+  (for [(def i 0) (< i (len s)) (set i (+ i 1))]
+    (set c (+ c (* (+ i 1) (aget s i)))))
+  c)
+
+(defn probe [s]
+  (cond full (checksum s)
+        (+ (* (aget s 0) 100000) (aget s (- (len s) 1)))))
+
+(defn append_all []
+  (def out (array))
+  (for [(def i 0) (< i (len data)) (set i (+ i 1))]
+    (set out (append out (aget data i))))
+  (probe out))
+
+(defn index_sum []
+  (def sum 0)
+  (for [(def i 0) (< i (len idx)) (set i (+ i 1))]
+    (set sum (+ sum (aget data (aget idx i)))))
+  sum)
+
+(defn slice_probe []
+  (def n (len data))
+  (probe (slice data (/ n 4) (/ (* 3 n) 4))))
+
+(defn reverse_probe []
+  (def n (len data))
+  (def out (makeArray n))
+  (for [(def i 0) (< i n) (set i (+ i 1))]
+    (aset out i (aget data (- n i 1))))
+  (probe out))
+
+(defn sort_probe [] (probe (sortBy data (fn [a b] (> a b)))))
+
+(defn contains [s x]
+  (def found false)
+  (for [(def i 0) (and (not found) (< i (len s))) (set i (+ i 1))]
+    (cond (== (aget s i) x) (set found true) 0))
+  found)
+
+(defn contains_count []
+  (def c 0)
+  (for [(def j 0) (< j 100) (set j (+ j 1))]
+    (cond (contains data (* j 2)) (set c (+ c 1)) 0))
+  c)
+`)
+	MustSuccess(t, err)
+	toArray := func(nums []int64) *zygo.SexpArray {
+		arr := make([]zygo.Sexp, len(nums))
+		for i, v := range nums {
+			arr[i] = &zygo.SexpInt{Val: v}
+		}
+		return &zygo.SexpArray{Val: arr, Env: env}
+	}
+	find := func(name string) *zygo.SexpFunction {
+		v, _ := env.FindObject(name)
+		return v.(*zygo.SexpFunction)
+	}
+	setup := find("setup")
+	setFull := find("set_full")
+	for _, n := range seqSizes {
+		values, idx := seqData(n)
+		_, err := env.Apply(setup, []zygo.Sexp{toArray(values), toArray(idx)})
+		MustSuccess(t, err)
+		for _, op := range seqOps(values, idx) {
+			fn := find(op.fn)
+			_, err = env.Apply(setFull, []zygo.Sexp{&zygo.SexpBool{Val: true}})
+			MustSuccess(t, err)
+			res, err := env.Apply(fn, []zygo.Sexp{})
+			MustSuccess(t, err)
+			MustEqualInt64(t, op.checksum, res.(*zygo.SexpInt).Val)
+			_, err = env.Apply(setFull, []zygo.Sexp{&zygo.SexpBool{Val: false}})
+			MustSuccess(t, err)
+			subBenchmark(t, fmt.Sprintf("n=%d/%s", n, op.name), func(t *testing.B) {
+				for i := 0; i < t.N; i++ {
+					res, err := env.Apply(fn, []zygo.Sexp{})
+					MustSuccess(t, err)
+					num, ok := res.(*zygo.SexpInt)
+					MustTrue(t, ok)
+					MustEqualInt64(t, op.expect, num.Val)
+				}
+			})
+		}
+	}
+}
+
+// glispInts converts Go integers into a glisp array.
+func glispInts(nums []int64) glisp.SexpArray {
+	arr := make(glisp.SexpArray, len(nums))
+	for i, v := range nums {
+		arr[i] = glisp.NewSexpInt64(v)
+	}
+	return arr
+}
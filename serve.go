@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// resultsServer serves the runs in dir: an index, one report per run and
+// the comparison of two runs. Every page is rendered on the server with
+// inline SVG, so it works without network access.
+type resultsServer struct {
+	dir string
+}
+
+// runSummary is one run on the index page.
+type runSummary struct {
+	ID        string
+	Started   string
+	GoVersion string
+	Glisp     string
+	Results   int
+}
+
+// pageFilter is the engine and workload filter of every page, taken from
+// the query string.
+type pageFilter struct {
+	Engine   string
+	Workload string
+	Error    string
+}
+
+func newPageFilter(r *http.Request) pageFilter {
+	return pageFilter{Engine: r.FormValue("engine"), Workload: r.FormValue("workload")}
+}
+
+// apply returns the results selected by f. An invalid workload regexp
+// selects nothing and is reported on the page.
+func (f *pageFilter) apply(results []benchResult) []benchResult {
+	var re *regexp.Regexp
+	if f.Workload != "" {
+		var err error
+		if re, err = regexp.Compile(f.Workload); err != nil {
+			f.Error = err.Error()
+			return nil
+		}
+	}
+	engines := make(map[string]bool)
+	for _, e := range strings.Split(f.Engine, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			engines[e] = true
+		}
+	}
+	var out []benchResult
+	for _, r := range results {
+		if len(engines) > 0 && !engines[r.Engine] {
+			continue
+		}
+		if re != nil && !re.MatchString(r.Workload) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (s *resultsServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /run/{id}", s.run)
+	mux.HandleFunc("GET /compare", s.compare)
+	return mux
+}
+
+// runIDs returns the IDs of the runs in the results directory, newest
+// first. Only these are ever opened, so request paths never reach the
+// file system.
+func (s *resultsServer) runIDs() ([]string, error) {
+	files, err := listRuns(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(files))
+	for i, f := range files {
+		ids[len(files)-1-i] = strings.TrimSuffix(filepath.Base(f), ".json")
+	}
+	return ids, nil
+}
+
+func (s *resultsServer) load(id string) (*benchRun, error) {
+	ids, err := s.runIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, known := range ids {
+		if known == id {
+			return loadRun(filepath.Join(s.dir, id+".json"))
+		}
+	}
+	return nil, fmt.Errorf("no run %q in %s", id, s.dir)
+}
+
+func (s *resultsServer) index(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.runIDs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var runs []runSummary
+	for _, id := range ids {
+		run, err := s.load(id)
+		if err != nil {
+			runs = append(runs, runSummary{ID: id, Started: err.Error()})
+			continue
+		}
+		runs = append(runs, runSummary{
+			ID:        id,
+			Started:   run.Started.Format("2006-01-02 15:04:05"),
+			GoVersion: run.GoVersion,
+			Glisp:     run.Modules[glispModule],
+			Results:   len(run.Results),
+		})
+	}
+	s.render(w, "index", map[string]interface{}{"Dir": s.dir, "Runs": runs})
+}
+
+func (s *resultsServer) run(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	run, err := s.load(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	filter := newPageFilter(r)
+	run.Results = filter.apply(run.Results)
+	report := newHTMLReport(run)
+	var nav bytes.Buffer
+	if err := serveTemplates.ExecuteTemplate(&nav, "nav", map[string]interface{}{
+		// the file name, not the ID stored in it, is what load finds.
+		"Action": "/run/" + id,
+		"Filter": filter,
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Nav = template.HTML(nav.String())
+	var page bytes.Buffer
+	if err := reportTemplate.Execute(&page, report); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+func (s *resultsServer) compare(w http.ResponseWriter, r *http.Request) {
+	old, err := s.load(r.FormValue("old"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	new, err := s.load(r.FormValue("new"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	filter := newPageFilter(r)
+	old.Results = filter.apply(old.Results)
+	new.Results = filter.apply(new.Results)
+	comparisons := compareRuns(old, new)
+	sort.SliceStable(comparisons, func(i, j int) bool { return comparisons[i].Delta < comparisons[j].Delta })
+
+	categories := make([]string, len(comparisons))
+	series := []chartSeries{
+		{Name: old.ID, Values: make([]float64, len(comparisons)), Color: "#bab0ac"},
+		{Name: new.ID, Values: make([]float64, len(comparisons)), Color: "#4e79a7"},
+	}
+	for i, c := range comparisons {
+		categories[i] = c.Workload + "_" + c.Engine
+		series[0].Values[i] = opsPerMs(c.OldNs)
+		series[1].Values[i] = opsPerMs(c.NewNs)
+	}
+	var chart template.HTML
+	if len(comparisons) > 0 {
+		chart = template.HTML(groupedBarChart("Throughput", "op/ms", categories, series, true))
+	}
+	s.render(w, "compare", map[string]interface{}{
+		"Old":         old,
+		"New":         new,
+		"Filter":      filter,
+		"Action":      "/compare",
+		"Comparisons": comparisons,
+		"Chart":       chart,
+	})
+}
+
+func (s *resultsServer) render(w http.ResponseWriter, name string, data interface{}) {
+	var page bytes.Buffer
+	if err := serveTemplates.ExecuteTemplate(&page, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+var serveTemplates = template.Must(template.New("serve").Funcs(template.FuncMap{
+	"ns":    formatNs,
+	"delta": func(d float64) string { return fmt.Sprintf("%+.2f%%", d) },
+	"deltaColor": func(d float64) string {
+		switch {
+		case d <= -5:
+			return "#2ca02c"
+		case d >= 5:
+			return "#d62728"
+		}
+		return "inherit"
+	},
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>glisp-benchmark results</title>
+<style>
+` + reportStyle + `form { margin: 1em 0; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "nav"}}<p><a href="/">All runs</a></p>
+<form action="{{.Action}}" method="get">
+Engines <input name="engine" value="{{.Filter.Engine}}" placeholder="glisp,lua">
+Workload <input name="workload" value="{{.Filter.Workload}}" placeholder="regexp">
+<button>Filter</button>
+{{if .Filter.Error}}<b>{{.Filter.Error}}</b>{{end}}
+</form>
+{{end}}
+
+{{define "index"}}{{template "head"}}
+<h1>Runs in {{.Dir}}</h1>
+{{if .Runs}}<form action="/compare" method="get">
+<table>
+<tr><th>Run</th><th>Started</th><th>Go</th><th>glisp</th><th>Results</th><th>Old</th><th>New</th></tr>
+{{range $i, $r := .Runs}}<tr>
+<td><a href="/run/{{$r.ID}}">{{$r.ID}}</a></td><td>{{$r.Started}}</td><td>{{$r.GoVersion}}</td><td>{{$r.Glisp}}</td><td>{{$r.Results}}</td>
+<td><input type="radio" name="old" value="{{$r.ID}}"{{if eq $i 1}} checked{{end}}></td>
+<td><input type="radio" name="new" value="{{$r.ID}}"{{if eq $i 0}} checked{{end}}></td>
+</tr>
+{{end}}</table>
+Engines <input name="engine" placeholder="glisp,lua">
+Workload <input name="workload" placeholder="regexp">
+<button>Compare</button>
+</form>
+{{else}}<p>No runs yet. Create one with <code>glisp-benchmark run</code>.</p>{{end}}
+</body>
+</html>
+{{end}}
+
+{{define "compare"}}{{template "head"}}
+<p><a href="/">All runs</a></p>
+<form action="{{.Action}}" method="get">
+<input type="hidden" name="old" value="{{.Old.ID}}">
+<input type="hidden" name="new" value="{{.New.ID}}">
+Engines <input name="engine" value="{{.Filter.Engine}}" placeholder="glisp,lua">
+Workload <input name="workload" value="{{.Filter.Workload}}" placeholder="regexp">
+<button>Filter</button>
+{{if .Filter.Error}}<b>{{.Filter.Error}}</b>{{end}}
+</form>
+<h1><a href="/run/{{.Old.ID}}">{{.Old.ID}}</a> &rarr; <a href="/run/{{.New.ID}}">{{.New.ID}}</a></h1>
+{{if .Comparisons}}
+<table>
+<tr><th>Benchmark</th><th>Procs</th><th>old ns/op</th><th>new ns/op</th><th>delta</th></tr>
+{{range .Comparisons}}<tr><td>{{.Workload}}_{{.Engine}}</td><td>{{.Procs}}</td><td>{{ns .OldNs}}</td><td>{{ns .NewNs}}</td><td style="color: {{deltaColor .Delta}}">{{delta .Delta}}</td></tr>
+{{end}}</table>
+{{.Chart}}
+{{else}}<p>The runs have no benchmark in common.</p>{{end}}
+</body>
+</html>
+{{end}}
+`))
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The benchmark sources are embedded so that reports can show what each
+// engine actually runs without access to the checkout.
+//
+//go:embed *_bench.go
+var benchFS embed.FS
+
+// benchSources indexes the top-level declarations of the embedded files by
+// name, as source text.
+type benchSources struct {
+	funcs map[string]*ast.FuncDecl
+	// values holds package-level vars and consts, which is where shared
+	// scripts and case tables live.
+	values map[string]string
+	fset   *token.FileSet
+	files  map[string][]byte
+}
+
+var (
+	loadSourcesOnce sync.Once
+	loadedSources   *benchSources
+)
+
+func loadBenchSources() *benchSources {
+	loadSourcesOnce.Do(func() {
+		src := &benchSources{
+			funcs:  make(map[string]*ast.FuncDecl),
+			values: make(map[string]string),
+			fset:   token.NewFileSet(),
+			files:  make(map[string][]byte),
+		}
+		names, _ := fs.Glob(benchFS, "*_bench.go")
+		for _, name := range names {
+			data, err := benchFS.ReadFile(name)
+			if err != nil {
+				continue
+			}
+			f, err := parser.ParseFile(src.fset, name, data, parser.ParseComments)
+			if err != nil {
+				continue
+			}
+			src.files[name] = data
+			for _, decl := range f.Decls {
+				switch decl := decl.(type) {
+				case *ast.FuncDecl:
+					if decl.Recv == nil {
+						src.funcs[decl.Name.Name] = decl
+					}
+				case *ast.GenDecl:
+					if decl.Tok != token.VAR && decl.Tok != token.CONST {
+						continue
+					}
+					for _, spec := range decl.Specs {
+						vs := spec.(*ast.ValueSpec)
+						text := decl.Tok.String() + " " + src.text(spec)
+						for _, id := range vs.Names {
+							src.values[id.Name] = text
+						}
+					}
+				}
+			}
+		}
+		loadedSources = src
+	})
+	return loadedSources
+}
+
+func (s *benchSources) text(node ast.Node) string {
+	start, end := s.fset.Position(node.Pos()), s.fset.Position(node.End())
+	return string(s.files[start.Filename][start.Offset:end.Offset])
+}
+
+// benchmarkSource returns the Go source of bench<workload>_<engine>, with
+// its doc comment and the package-level values it refers to, or "" if there
+// is no such function. workload is a top-level name, without sub-benchmark.
+func benchmarkSource(workload, engine string) string {
+	s := loadBenchSources()
+	fn, ok := s.funcs["bench"+workload+"_"+engine]
+	if !ok {
+		return ""
+	}
+	var out bytes.Buffer
+	if fn.Doc != nil {
+		out.WriteString(s.text(fn.Doc) + "\n")
+	}
+	out.WriteString(s.text(fn))
+	var refs []string
+	seen := make(map[string]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if ok && !seen[id.Name] {
+			if _, ok := s.values[id.Name]; ok {
+				seen[id.Name] = true
+				refs = append(refs, s.values[id.Name])
+			}
+		}
+		return true
+	})
+	for _, ref := range refs {
+		out.WriteString("\n\n" + ref)
+	}
+	return out.String()
+}
+
+// workloadSource is benchmarkSource for the testdata workloads, which are
+// reported as <benchmark>/<name> and loaded from dir/<name>: it returns the
+// script file of engine.
+func workloadSource(dir, name, engine string) string {
+	data, err := fs.ReadFile(testdataFS, path.Join(dir, name, workloadScripts[engine]))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// sourceListing is the source of one workload in every engine that runs it.
+type sourceListing struct {
+	Workload string
+	Engines  []engineSource
+}
+
+type engineSource struct {
+	Engine string
+	Source string
+}
+
+// workloadSources returns a listing per top-level workload in summaries,
+// and one per testdata workload instead of the shared Workloads and Macro
+// benchmarks.
+func workloadSources(summaries []summary) []sourceListing {
+	engines := engineOrder(summaries)
+	var listings []sourceListing
+	seen := make(map[string]bool)
+	for _, sm := range summaries {
+		top := strings.SplitN(sm.Workload, "/", 2)[0]
+		name, lookup := top, benchmarkSource
+		if dir, ok := workloadDir(top); ok && strings.Contains(sm.Workload, "/") {
+			name, lookup = sm.Workload, func(_, engine string) string {
+				return workloadSource(dir, strings.TrimPrefix(sm.Workload, top+"/"), engine)
+			}
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		listing := sourceListing{Workload: name}
+		for _, e := range engines {
+			if src := lookup(top, e); src != "" {
+				listing.Engines = append(listing.Engines, engineSource{e, src})
+			}
+		}
+		if len(listing.Engines) > 0 {
+			listings = append(listings, listing)
+		}
+	}
+	return listings
+}
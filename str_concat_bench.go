@@ -11,7 +11,7 @@ import (
 	"github.com/yuin/gopher-lua"
 )
 
-func BenchmarkStringConcat_glisp(t *testing.B) {
+func benchStringConcat_glisp(t *testing.B) {
 	vm := glisp.New()
 	ext.ImportCoreUtils(vm)
 	vm.SourceStream(bytes.NewBufferString(`(defn string-concat [a b c] (concat a b c))`))
@@ -24,7 +24,7 @@ func BenchmarkStringConcat_glisp(t *testing.B) {
 	}
 }
 
-func BenchmarkStringConcat_goja(t *testing.B) {
+func benchStringConcat_goja(t *testing.B) {
 	vm := goja.New()
 	_, err := vm.RunString(`
 	function stringConcat(a, b, c) {
@@ -41,7 +41,7 @@ func BenchmarkStringConcat_goja(t *testing.B) {
 	}
 }
 
-func BenchmarkStringConcat_lua(t *testing.B) {
+func benchStringConcat_lua(t *testing.B) {
 	L := lua.NewState()
 	defer L.Close()
 	err := L.DoString(`
@@ -63,7 +63,7 @@ func BenchmarkStringConcat_lua(t *testing.B) {
 	}
 }
 
-func BenchmarkStringConcat_zygo(t *testing.B) {
+func benchStringConcat_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	for i := 0; i < t.N; i++ {
 		res, err := env.EvalString(`(concat "hello" " " "world")`)
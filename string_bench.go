@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// stringWorkload is one string operation; every engine defines a script
+// function named fn which must turn arg into expect.
+type stringWorkload struct {
+	name   string
+	fn     string
+	arg    string
+	expect string
+}
+
+var stringWorkloads = []stringWorkload{
+	{"split-join", "split_join", "alpha,beta,gamma,delta,epsilon", "alpha|beta|gamma|delta|epsilon"},
+	{"substring", "substring", "hello glisp benchmark", "glisp"},
+	{"upper-lower", "change_case", "Hello World", "HELLO WORLD hello world"},
+	{"trim", "trim_space", "  \t hello world \n ", "hello world"},
+	{"replace", "replace_all", "the quick brown fox jumps over the lazy dog", "a quick brown fox jumps over a lazy dog"},
+	{"build-10k", "build_string", "0123456789", strings.Repeat("0123456789", 1000)},
+	{"sprintf", "format_record", "glisp", "glisp has 42 items costing 3.50"},
+	{"utf8", "utf8_slice", "2006年01月02日", "11 年01"},
+}
+
+func benchStringOps_glisp(t *testing.B) {
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	ext.ImportString(vm)
+	err := vm.SourceStream(bytes.NewBufferString(`
+(defn split_join [s] (str/join (str/split s ",") "|"))
+
+(defn substring [s] (slice s 6 11))
+
+(defn change_case [s] (concat (str/upper s) " " (str/lower s)))
+
+(defn trim_space [s] (str/trim-space s))
+
+(defn replace_all [s] (str/replace s "the" "a"))
+
+(defn build_loop [acc piece n]
+  (cond (= n 0) acc
+        (build_loop (concat acc piece) piece (- n 1))))
+
+(defn build_string [piece] (build_loop "" piece 1000))
+
+(defn format_record [name] (sprintf "%s has %d items costing %.2f" name 42 3.5))
+
+(defn utf8_slice [s] (sprintf "%d %s" (len s) (slice s 4 7)))
+`))
+	MustSuccess(t, err)
+	for _, w := range stringWorkloads {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				v, err := vm.ApplyByName(w.fn, glisp.MakeArgs(glisp.SexpStr(w.arg)))
+				MustSuccess(t, err)
+				s, ok := v.(glisp.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, string(s))
+			}
+		})
+	}
+}
+
+func benchStringOps_goja(t *testing.B) {
+	const SCRIPT = `
+function split_join(s) {
+	return s.split(",").join("|");
+}
+
+function substring(s) {
+	return s.substring(6, 11);
+}
+
+function change_case(s) {
+	return s.toUpperCase() + " " + s.toLowerCase();
+}
+
+function trim_space(s) {
+	return s.trim();
+}
+
+function replace_all(s) {
+	return s.replaceAll("the", "a");
+}
+
+function build_string(piece) {
+	let s = "";
+	for (let i = 0; i < 1000; i++) {
+		s += piece;
+	}
+	return s;
+}
+
+function format_record(name) {
+	return ` + "`${name} has ${42} items costing ${(3.5).toFixed(2)}`" + `;
+}
+
+function utf8_slice(s) {
+	const runes = Array.from(s);
+	return runes.length + " " + runes.slice(4, 7).join("");
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	for _, w := range stringWorkloads {
+		f, ok := goja.AssertFunction(vm.Get(w.fn))
+		MustTrue(t, ok)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := f(goja.Undefined(), vm.ToValue(w.arg))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.String())
+			}
+		})
+	}
+}
+
+func benchStringOps_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	// go-lua implements neither Lua patterns nor the utf8 library, so
+	// splitting, trimming and rune indexing are done with plain finds and bytes.
+	script := `
+function split_join(s)
+  local parts = {}
+  local start = 1
+  while true do
+    local i, j = string.find(s, ",", start, true)
+    if i == nil then
+      break
+    end
+    parts[#parts + 1] = string.sub(s, start, i - 1)
+    start = j + 1
+  end
+  parts[#parts + 1] = string.sub(s, start)
+  return table.concat(parts, "|")
+end
+
+function substring(s)
+  return string.sub(s, 7, 11)
+end
+
+function change_case(s)
+  return string.upper(s) .. " " .. string.lower(s)
+end
+
+local function is_space(b)
+  return b == 32 or b == 9 or b == 10 or b == 13
+end
+
+function trim_space(s)
+  local i, j = 1, #s
+  while i <= j and is_space(string.byte(s, i)) do
+    i = i + 1
+  end
+  while j >= i and is_space(string.byte(s, j)) do
+    j = j - 1
+  end
+  return string.sub(s, i, j)
+end
+
+function replace_all(s)
+  local out = {}
+  local start = 1
+  while true do
+    local i, j = string.find(s, "the", start, true)
+    if i == nil then
+      break
+    end
+    out[#out + 1] = string.sub(s, start, i - 1)
+    out[#out + 1] = "a"
+    start = j + 1
+  end
+  out[#out + 1] = string.sub(s, start)
+  return table.concat(out)
+end
+
+function build_string(piece)
+  local s = ""
+  for i = 1, 1000 do
+    s = s .. piece
+  end
+  return s
+end
+
+function format_record(name)
+  return string.format("%s has %d items costing %.2f", name, 42, 3.5)
+end
+
+function utf8_slice(s)
+  local starts = {}
+  for i = 1, #s do
+    local b = string.byte(s, i)
+    if b < 0x80 or b >= 0xC0 then
+      starts[#starts + 1] = i
+    end
+  end
+  starts[#starts + 1] = #s + 1
+  return string.format("%d %s", #starts - 1, string.sub(s, starts[5], starts[8] - 1))
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	for _, w := range stringWorkloads {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				l.Global(w.fn)
+				l.PushString(w.arg)
+				err = l.ProtectedCall(1, 1, 0)
+				MustSuccess(t, err)
+				s, ok := l.ToString(-1)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s)
+				l.Pop(1)
+			}
+		})
+	}
+}
+
+func benchStringOps_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	// zygo strings are plain byte slices without case, replace or rune
+	// helpers, so those are registered from Go.
+	env.AddFunction("toUpper", zygoStringFunc(1, func(args []string) string { return strings.ToUpper(args[0]) }))
+	env.AddFunction("toLower", zygoStringFunc(1, func(args []string) string { return strings.ToLower(args[0]) }))
+	env.AddFunction("replaceAll", zygoStringFunc(3, func(args []string) string { return strings.ReplaceAll(args[0], args[1], args[2]) }))
+	env.AddFunction("runeLen", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 1 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		s, ok := args[0].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("%s: argument must be a string", name)
+		}
+		return &zygo.SexpInt{Val: int64(len([]rune(s.S)))}, nil
+	})
+	env.AddFunction("runeSlice", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 3 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		s, ok := args[0].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("%s: argument 1 must be a string", name)
+		}
+		start, ok := args[1].(*zygo.SexpInt)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("%s: argument 2 must be an int", name)
+		}
+		end, ok := args[2].(*zygo.SexpInt)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("%s: argument 3 must be an int", name)
+		}
+		runes := []rune(s.S)
+		if start.Val < 0 || end.Val < start.Val || end.Val > int64(len(runes)) {
+			return zygo.SexpNull, fmt.Errorf("%s: range [%d:%d] out of bounds for length %d", name, start.Val, end.Val, len(runes))
+		}
+		return &zygo.SexpStr{S: string(runes[start.Val:end.Val])}, nil
+	})
+	_, err := env.EvalString(`
+(defn split_join [s]
+  (def parts (split s ","))
+  (def out (aget parts 0))
+  (for [(def i 1) (< i (len parts)) (set i (+ i 1))]
+    (set out (concat out "|" (aget parts i))))
+  out)
+
+(defn substring [s] (slice s 6 11))
+
+(defn change_case [s] (concat (toUpper s) " " (toLower s)))
+
+(defn trim_space [s] (trim s))
+
+(defn replace_all [s] (replaceAll s "the" "a"))
+
+(defn build_string [piece]
+  (def out "")
+  (for [(def i 0) (< i 1000) (set i (+ i 1))]
+    (set out (concat out piece)))
+  out)
+
+(defn format_record [name] (sprintf "%s has %d items costing %.2f" name 42 3.5))
+
+(defn utf8_slice [s] (sprintf "%d %s" (runeLen s) (runeSlice s 4 7)))
+`)
+	MustSuccess(t, err)
+	for _, w := range stringWorkloads {
+		v, _ := env.FindObject(w.fn)
+		fn := v.(*zygo.SexpFunction)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := env.Apply(fn, []zygo.Sexp{&zygo.SexpStr{S: w.arg}})
+				MustSuccess(t, err)
+				s, ok := res.(*zygo.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s.S)
+			}
+		})
+	}
+}
+
+// zygoStringFunc adapts a Go function over nargs string arguments into a zygo
+// builtin.
+func zygoStringFunc(nargs int, f func(args []string) string) zygo.ZlispUserFunction {
+	return func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != nargs {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			s, ok := arg.(*zygo.SexpStr)
+			if !ok {
+				return zygo.SexpNull, fmt.Errorf("%s: argument %d must be a string", name, i+1)
+			}
+			strs[i] = s.S
+		}
+		return &zygo.SexpStr{S: f(strs)}, nil
+	}
+}
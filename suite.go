@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+//go:generate go run gen_suite.go
+
+// suiteBenchmark is one top-level benchmark. Every bench<Workload>_<engine>
+// function is listed in suite by gen_suite.go, which also writes the
+// Benchmark<Workload>_<engine> wrappers that go test runs.
+type suiteBenchmark struct {
+	workload string
+	engine   string
+	fn       func(t *testing.B)
+}
+
+// subBenchmark runs f as the sub-benchmark name of t. It is t.Run under go
+// test; runSuite swaps it to record every sub-benchmark on its own, because
+// testing.Benchmark folds sub-benchmark results into their parent.
+var subBenchmark = func(t *testing.B, name string, f func(t *testing.B)) bool {
+	return t.Run(name, f)
+}
+
+// reportMetric is t.ReportMetric, swapped by runSuite so that metrics of
+// sub-benchmarks end up in their results too.
+var reportMetric = func(t *testing.B, n float64, unit string) {
+	t.ReportMetric(n, unit)
+}
+
+// suiteFilter selects what runSuite runs. Nil regexps and an empty engine
+// set match everything; cases only applies to sub-benchmarks.
+type suiteFilter struct {
+	workload *regexp.Regexp
+	cases    *regexp.Regexp
+	engines  map[string]bool
+}
+
+func (f suiteFilter) selected() []suiteBenchmark {
+	var out []suiteBenchmark
+	for _, s := range suite {
+		if f.workload != nil && !f.workload.MatchString(s.workload) {
+			continue
+		}
+		if len(f.engines) > 0 && !f.engines[s.engine] {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+var initTesting sync.Once
+
+// runSuite runs the selected benchmarks count times for every GOMAXPROCS
+// value in cpus, in-process through testing.Benchmark. benchtime takes the
+// same values as go test -benchtime. progress is called after each result.
+func runSuite(filter suiteFilter, benchtime string, count int, cpus []int, progress func(benchResult)) ([]benchResult, error) {
+	initTesting.Do(testing.Init)
+	if err := flag.Set("test.benchtime", benchtime); err != nil {
+		return nil, err
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	var results []benchResult
+	for _, cpu := range cpus {
+		runtime.GOMAXPROCS(cpu)
+		for i := 0; i < count; i++ {
+			for _, s := range filter.selected() {
+				for _, res := range runBenchmark(s, filter.cases) {
+					res.Procs = cpu
+					results = append(results, res)
+					if progress != nil {
+						progress(res)
+					}
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// runBenchmark runs one top-level benchmark and returns its result, or one
+// result per sub-benchmark when it has any. A sub-benchmark result is read
+// off the last b.N round like go test does; unlike go test its allocation
+// counts include time spent with the timer stopped. Runtime metrics are
+// taken from the last round the same way, timer or not.
+func runBenchmark(s suiteBenchmark, cases *regexp.Regexp) []benchResult {
+	var subs []benchResult
+	hasSubs := false
+	defer func(run func(*testing.B, string, func(*testing.B)) bool, report func(*testing.B, float64, string)) {
+		subBenchmark, reportMetric = run, report
+	}(subBenchmark, reportMetric)
+	subBenchmark = func(t *testing.B, name string, f func(t *testing.B)) bool {
+		hasSubs = true
+		if cases != nil && !cases.MatchString(name) {
+			return true
+		}
+		res := newBenchResult(s, name)
+		ok := t.Run(name, func(t *testing.B) {
+			extra := make(map[string]float64)
+			reportMetric = func(t *testing.B, n float64, unit string) {
+				extra[unit] = n
+				t.ReportMetric(n, unit)
+			}
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			rtBefore := readRuntime()
+			f(t)
+			rtAfter := readRuntime()
+			runtime.ReadMemStats(&after)
+			res.Runtime = runtimeDeltas(rtBefore, rtAfter, t.N)
+			res.setResult(testing.BenchmarkResult{
+				N:         t.N,
+				T:         t.Elapsed(),
+				MemAllocs: after.Mallocs - before.Mallocs,
+				MemBytes:  after.TotalAlloc - before.TotalAlloc,
+				Extra:     extra,
+			})
+		})
+		res.Failed = !ok
+		subs = append(subs, res)
+		return ok
+	}
+	var rt map[string]float64
+	top := testing.Benchmark(func(t *testing.B) {
+		before := readRuntime()
+		s.fn(t)
+		rt = runtimeDeltas(before, readRuntime(), t.N)
+	})
+	if hasSubs {
+		return subs
+	}
+	res := newBenchResult(s, "")
+	res.setResult(top)
+	res.Runtime = rt
+	res.Failed = top.N == 0
+	return []benchResult{res}
+}
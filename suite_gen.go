@@ -0,0 +1,97 @@
+// Code generated by gen_suite.go; DO NOT EDIT.
+
+package main
+
+var suite = []suiteBenchmark{
+	{"RuleEngine", "glisp", benchRuleEngine_glisp},
+	{"RuleEngine", "goja", benchRuleEngine_goja},
+	{"RuleEngine", "lua", benchRuleEngine_lua},
+	{"RuleEngine", "zygo", benchRuleEngine_zygo},
+	{"StructBinding", "glisp", benchStructBinding_glisp},
+	{"StructBinding", "goja", benchStructBinding_goja},
+	{"StructBinding", "lua", benchStructBinding_lua},
+	{"StructBinding", "zygo", benchStructBinding_zygo},
+	{"Cancel", "glisp", benchCancel_glisp},
+	{"Cancel", "goja", benchCancel_goja},
+	{"Cancel", "lua", benchCancel_lua},
+	{"Cancel", "gopherlua", benchCancel_gopherlua},
+	{"Cancel", "zygo", benchCancel_zygo},
+	{"ErrorPath", "glisp", benchErrorPath_glisp},
+	{"ErrorPath", "goja", benchErrorPath_goja},
+	{"ErrorPath", "lua", benchErrorPath_lua},
+	{"ErrorPath", "zygo", benchErrorPath_zygo},
+	{"Factorial", "glisp", benchFactorial_glisp},
+	{"Factorial", "goja", benchFactorial_goja},
+	{"Factorial", "lua", benchFactorial_lua},
+	{"Factorial", "zygo", benchFactorial_zygo},
+	{"RegexpMatch", "glisp", benchRegexpMatch_glisp},
+	{"RegexpMatch", "goja", benchRegexpMatch_goja},
+	{"RegexpMatch", "lua", benchRegexpMatch_lua},
+	{"RegexpMatch", "zygo", benchRegexpMatch_zygo},
+	{"ComplexCondition", "glisp", benchComplexCondition_glisp},
+	{"ComplexCondition", "goja", benchComplexCondition_goja},
+	{"ComplexCondition", "lua", benchComplexCondition_lua},
+	{"ComplexCondition", "zygo", benchComplexCondition_zygo},
+	{"FormatTime", "glisp", benchFormatTime_glisp},
+	{"FormatTime", "goja", benchFormatTime_goja},
+	{"FormatTime", "lua", benchFormatTime_lua},
+	{"FormatTime", "zygo", benchFormatTime_zygo},
+	{"HashWrite", "glisp", benchHashWrite_glisp},
+	{"HashWrite", "goja", benchHashWrite_goja},
+	{"HashWrite", "lua", benchHashWrite_lua},
+	{"HashWrite", "zygo", benchHashWrite_zygo},
+	{"HashDelete", "glisp", benchHashDelete_glisp},
+	{"HashDelete", "goja", benchHashDelete_goja},
+	{"HashDelete", "lua", benchHashDelete_lua},
+	{"HashDelete", "zygo", benchHashDelete_zygo},
+	{"HashAccess", "glisp", benchHashAccess_glisp},
+	{"HashAccess", "goja", benchHashAccess_goja},
+	{"HashAccess", "lua", benchHashAccess_lua},
+	{"HashAccess", "zygo", benchHashAccess_zygo},
+	{"JSONParseAndModify", "glisp", benchJSONParseAndModify_glisp},
+	{"JSONParseAndModify", "goja", benchJSONParseAndModify_goja},
+	{"JSONParseAndModify", "lua", benchJSONParseAndModify_lua},
+	{"JSONParseAndModify", "zygo", benchJSONParseAndModify_zygo},
+	{"HashScale", "glisp", benchHashScale_glisp},
+	{"HashScale", "goja", benchHashScale_goja},
+	{"HashScale", "lua", benchHashScale_lua},
+	{"HashScale", "zygo", benchHashScale_zygo},
+	{"FunctionLookup", "glisp", benchFunctionLookup_glisp},
+	{"FunctionLookup", "goja", benchFunctionLookup_goja},
+	{"FunctionLookup", "lua", benchFunctionLookup_lua},
+	{"FunctionLookup", "zygo", benchFunctionLookup_zygo},
+	{"Macro", "glisp", benchMacro_glisp},
+	{"Macro", "goja", benchMacro_goja},
+	{"Macro", "lua", benchMacro_lua},
+	{"Macro", "zygo", benchMacro_zygo},
+	{"MacroExpansion", "glisp", benchMacroExpansion_glisp},
+	{"MacroExpansion", "zygo", benchMacroExpansion_zygo},
+	{"RegexpOps", "glisp", benchRegexpOps_glisp},
+	{"RegexpOps", "goja", benchRegexpOps_goja},
+	{"RegexpOps", "lua", benchRegexpOps_lua},
+	{"RegexpOps", "zygo", benchRegexpOps_zygo},
+	{"VariableScope", "glisp", benchVariableScope_glisp},
+	{"VariableScope", "goja", benchVariableScope_goja},
+	{"VariableScope", "lua", benchVariableScope_lua},
+	{"VariableScope", "zygo", benchVariableScope_zygo},
+	{"Sequence", "glisp", benchSequence_glisp},
+	{"Sequence", "goja", benchSequence_goja},
+	{"Sequence", "lua", benchSequence_lua},
+	{"Sequence", "zygo", benchSequence_zygo},
+	{"StringConcat", "glisp", benchStringConcat_glisp},
+	{"StringConcat", "goja", benchStringConcat_goja},
+	{"StringConcat", "lua", benchStringConcat_lua},
+	{"StringConcat", "zygo", benchStringConcat_zygo},
+	{"StringOps", "glisp", benchStringOps_glisp},
+	{"StringOps", "goja", benchStringOps_goja},
+	{"StringOps", "lua", benchStringOps_lua},
+	{"StringOps", "zygo", benchStringOps_zygo},
+	{"TimeOps", "glisp", benchTimeOps_glisp},
+	{"TimeOps", "goja", benchTimeOps_goja},
+	{"TimeOps", "lua", benchTimeOps_lua},
+	{"TimeOps", "zygo", benchTimeOps_zygo},
+	{"Workloads", "glisp", benchWorkloads_glisp},
+	{"Workloads", "goja", benchWorkloads_goja},
+	{"Workloads", "lua", benchWorkloads_lua},
+	{"Workloads", "zygo", benchWorkloads_zygo},
+}
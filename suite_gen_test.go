@@ -0,0 +1,187 @@
+// Code generated by gen_suite.go; DO NOT EDIT.
+
+package main
+
+import "testing"
+
+func BenchmarkRuleEngine_glisp(t *testing.B) { benchRuleEngine_glisp(t) }
+
+func BenchmarkRuleEngine_goja(t *testing.B) { benchRuleEngine_goja(t) }
+
+func BenchmarkRuleEngine_lua(t *testing.B) { benchRuleEngine_lua(t) }
+
+func BenchmarkRuleEngine_zygo(t *testing.B) { benchRuleEngine_zygo(t) }
+
+func BenchmarkStructBinding_glisp(t *testing.B) { benchStructBinding_glisp(t) }
+
+func BenchmarkStructBinding_goja(t *testing.B) { benchStructBinding_goja(t) }
+
+func BenchmarkStructBinding_lua(t *testing.B) { benchStructBinding_lua(t) }
+
+func BenchmarkStructBinding_zygo(t *testing.B) { benchStructBinding_zygo(t) }
+
+func BenchmarkCancel_glisp(t *testing.B) { benchCancel_glisp(t) }
+
+func BenchmarkCancel_goja(t *testing.B) { benchCancel_goja(t) }
+
+func BenchmarkCancel_lua(t *testing.B) { benchCancel_lua(t) }
+
+func BenchmarkCancel_gopherlua(t *testing.B) { benchCancel_gopherlua(t) }
+
+func BenchmarkCancel_zygo(t *testing.B) { benchCancel_zygo(t) }
+
+func BenchmarkErrorPath_glisp(t *testing.B) { benchErrorPath_glisp(t) }
+
+func BenchmarkErrorPath_goja(t *testing.B) { benchErrorPath_goja(t) }
+
+func BenchmarkErrorPath_lua(t *testing.B) { benchErrorPath_lua(t) }
+
+func BenchmarkErrorPath_zygo(t *testing.B) { benchErrorPath_zygo(t) }
+
+func BenchmarkFactorial_glisp(t *testing.B) { benchFactorial_glisp(t) }
+
+func BenchmarkFactorial_goja(t *testing.B) { benchFactorial_goja(t) }
+
+func BenchmarkFactorial_lua(t *testing.B) { benchFactorial_lua(t) }
+
+func BenchmarkFactorial_zygo(t *testing.B) { benchFactorial_zygo(t) }
+
+func BenchmarkRegexpMatch_glisp(t *testing.B) { benchRegexpMatch_glisp(t) }
+
+func BenchmarkRegexpMatch_goja(t *testing.B) { benchRegexpMatch_goja(t) }
+
+func BenchmarkRegexpMatch_lua(t *testing.B) { benchRegexpMatch_lua(t) }
+
+func BenchmarkRegexpMatch_zygo(t *testing.B) { benchRegexpMatch_zygo(t) }
+
+func BenchmarkComplexCondition_glisp(t *testing.B) { benchComplexCondition_glisp(t) }
+
+func BenchmarkComplexCondition_goja(t *testing.B) { benchComplexCondition_goja(t) }
+
+func BenchmarkComplexCondition_lua(t *testing.B) { benchComplexCondition_lua(t) }
+
+func BenchmarkComplexCondition_zygo(t *testing.B) { benchComplexCondition_zygo(t) }
+
+func BenchmarkFormatTime_glisp(t *testing.B) { benchFormatTime_glisp(t) }
+
+func BenchmarkFormatTime_goja(t *testing.B) { benchFormatTime_goja(t) }
+
+func BenchmarkFormatTime_lua(t *testing.B) { benchFormatTime_lua(t) }
+
+func BenchmarkFormatTime_zygo(t *testing.B) { benchFormatTime_zygo(t) }
+
+func BenchmarkHashWrite_glisp(t *testing.B) { benchHashWrite_glisp(t) }
+
+func BenchmarkHashWrite_goja(t *testing.B) { benchHashWrite_goja(t) }
+
+func BenchmarkHashWrite_lua(t *testing.B) { benchHashWrite_lua(t) }
+
+func BenchmarkHashWrite_zygo(t *testing.B) { benchHashWrite_zygo(t) }
+
+func BenchmarkHashDelete_glisp(t *testing.B) { benchHashDelete_glisp(t) }
+
+func BenchmarkHashDelete_goja(t *testing.B) { benchHashDelete_goja(t) }
+
+func BenchmarkHashDelete_lua(t *testing.B) { benchHashDelete_lua(t) }
+
+func BenchmarkHashDelete_zygo(t *testing.B) { benchHashDelete_zygo(t) }
+
+func BenchmarkHashAccess_glisp(t *testing.B) { benchHashAccess_glisp(t) }
+
+func BenchmarkHashAccess_goja(t *testing.B) { benchHashAccess_goja(t) }
+
+func BenchmarkHashAccess_lua(t *testing.B) { benchHashAccess_lua(t) }
+
+func BenchmarkHashAccess_zygo(t *testing.B) { benchHashAccess_zygo(t) }
+
+func BenchmarkJSONParseAndModify_glisp(t *testing.B) { benchJSONParseAndModify_glisp(t) }
+
+func BenchmarkJSONParseAndModify_goja(t *testing.B) { benchJSONParseAndModify_goja(t) }
+
+func BenchmarkJSONParseAndModify_lua(t *testing.B) { benchJSONParseAndModify_lua(t) }
+
+func BenchmarkJSONParseAndModify_zygo(t *testing.B) { benchJSONParseAndModify_zygo(t) }
+
+func BenchmarkHashScale_glisp(t *testing.B) { benchHashScale_glisp(t) }
+
+func BenchmarkHashScale_goja(t *testing.B) { benchHashScale_goja(t) }
+
+func BenchmarkHashScale_lua(t *testing.B) { benchHashScale_lua(t) }
+
+func BenchmarkHashScale_zygo(t *testing.B) { benchHashScale_zygo(t) }
+
+func BenchmarkFunctionLookup_glisp(t *testing.B) { benchFunctionLookup_glisp(t) }
+
+func BenchmarkFunctionLookup_goja(t *testing.B) { benchFunctionLookup_goja(t) }
+
+func BenchmarkFunctionLookup_lua(t *testing.B) { benchFunctionLookup_lua(t) }
+
+func BenchmarkFunctionLookup_zygo(t *testing.B) { benchFunctionLookup_zygo(t) }
+
+func BenchmarkMacro_glisp(t *testing.B) { benchMacro_glisp(t) }
+
+func BenchmarkMacro_goja(t *testing.B) { benchMacro_goja(t) }
+
+func BenchmarkMacro_lua(t *testing.B) { benchMacro_lua(t) }
+
+func BenchmarkMacro_zygo(t *testing.B) { benchMacro_zygo(t) }
+
+func BenchmarkMacroExpansion_glisp(t *testing.B) { benchMacroExpansion_glisp(t) }
+
+func BenchmarkMacroExpansion_zygo(t *testing.B) { benchMacroExpansion_zygo(t) }
+
+func BenchmarkRegexpOps_glisp(t *testing.B) { benchRegexpOps_glisp(t) }
+
+func BenchmarkRegexpOps_goja(t *testing.B) { benchRegexpOps_goja(t) }
+
+func BenchmarkRegexpOps_lua(t *testing.B) { benchRegexpOps_lua(t) }
+
+func BenchmarkRegexpOps_zygo(t *testing.B) { benchRegexpOps_zygo(t) }
+
+func BenchmarkVariableScope_glisp(t *testing.B) { benchVariableScope_glisp(t) }
+
+func BenchmarkVariableScope_goja(t *testing.B) { benchVariableScope_goja(t) }
+
+func BenchmarkVariableScope_lua(t *testing.B) { benchVariableScope_lua(t) }
+
+func BenchmarkVariableScope_zygo(t *testing.B) { benchVariableScope_zygo(t) }
+
+func BenchmarkSequence_glisp(t *testing.B) { benchSequence_glisp(t) }
+
+func BenchmarkSequence_goja(t *testing.B) { benchSequence_goja(t) }
+
+func BenchmarkSequence_lua(t *testing.B) { benchSequence_lua(t) }
+
+func BenchmarkSequence_zygo(t *testing.B) { benchSequence_zygo(t) }
+
+func BenchmarkStringConcat_glisp(t *testing.B) { benchStringConcat_glisp(t) }
+
+func BenchmarkStringConcat_goja(t *testing.B) { benchStringConcat_goja(t) }
+
+func BenchmarkStringConcat_lua(t *testing.B) { benchStringConcat_lua(t) }
+
+func BenchmarkStringConcat_zygo(t *testing.B) { benchStringConcat_zygo(t) }
+
+func BenchmarkStringOps_glisp(t *testing.B) { benchStringOps_glisp(t) }
+
+func BenchmarkStringOps_goja(t *testing.B) { benchStringOps_goja(t) }
+
+func BenchmarkStringOps_lua(t *testing.B) { benchStringOps_lua(t) }
+
+func BenchmarkStringOps_zygo(t *testing.B) { benchStringOps_zygo(t) }
+
+func BenchmarkTimeOps_glisp(t *testing.B) { benchTimeOps_glisp(t) }
+
+func BenchmarkTimeOps_goja(t *testing.B) { benchTimeOps_goja(t) }
+
+func BenchmarkTimeOps_lua(t *testing.B) { benchTimeOps_lua(t) }
+
+func BenchmarkTimeOps_zygo(t *testing.B) { benchTimeOps_zygo(t) }
+
+func BenchmarkWorkloads_glisp(t *testing.B) { benchWorkloads_glisp(t) }
+
+func BenchmarkWorkloads_goja(t *testing.B) { benchWorkloads_goja(t) }
+
+func BenchmarkWorkloads_lua(t *testing.B) { benchWorkloads_lua(t) }
+
+func BenchmarkWorkloads_zygo(t *testing.B) { benchWorkloads_zygo(t) }
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Charts are plain SVG strings with no scripts, fonts or stylesheets, so
+// they render the same inline in HTML and as files on GitHub.
+
+var engineColors = map[string]string{
+	"glisp":     "#4e79a7",
+	"goja":      "#f28e2b",
+	"lua":       "#59a14f",
+	"gopherlua": "#76b7b2",
+	"zygo":      "#e15759",
+}
+
+func engineColor(engine string) string {
+	if c, ok := engineColors[engine]; ok {
+		return c
+	}
+	return "#9c755f"
+}
+
+// chartSeries is one line or bar group of a chart. Values that are zero or
+// negative are missing and not drawn. Color defaults to the engine colour
+// of Name.
+type chartSeries struct {
+	Name   string
+	Values []float64
+	Color  string
+}
+
+func (s chartSeries) color() string {
+	if s.Color != "" {
+		return s.Color
+	}
+	return engineColor(s.Name)
+}
+
+const chartFont = `font-family="sans-serif" font-size="11"`
+
+func formatNumber(v float64) string {
+	switch {
+	case v >= 100:
+		return strconv.FormatFloat(math.Round(v), 'f', 0, 64)
+	case v >= 1:
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// formatTick labels a power of ten on a log axis: 1, 10, 100, 1k, 10k...
+func formatTick(v float64) string {
+	for _, unit := range []struct {
+		scale  float64
+		suffix string
+	}{{1e9, "G"}, {1e6, "M"}, {1e3, "k"}} {
+		if v >= unit.scale {
+			return strconv.FormatFloat(v/unit.scale, 'g', -1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func svgOpen(b *strings.Builder, width, height int) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" %s>`+"\n", width, height, width, height, chartFont)
+	fmt.Fprintf(b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+}
+
+func svgText(b *strings.Builder, x, y float64, anchor, text string) {
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`+"\n", x, y, anchor, html.EscapeString(text))
+}
+
+// barChart draws one horizontal bar per label, coloured by engine.
+func barChart(title, unit string, labels []string, values []float64) string {
+	const width, labelWidth, barHeight, gap, top = 420, 80, 16, 6, 28
+	plot := float64(width - labelWidth - 70)
+	height := top + len(labels)*(barHeight+gap) + 6
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	var b strings.Builder
+	svgOpen(&b, width, height)
+	fmt.Fprintf(&b, `<text x="4" y="16" font-weight="bold">%s</text>`+"\n", html.EscapeString(title+" ("+unit+")"))
+	for i, label := range labels {
+		y := float64(top + i*(barHeight+gap))
+		svgText(&b, labelWidth-6, y+barHeight-4, "end", label)
+		if values[i] <= 0 || max == 0 {
+			svgText(&b, labelWidth+4, y+barHeight-4, "start", "n/a")
+			continue
+		}
+		w := math.Max(1, values[i]/max*plot)
+		fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%.1f" height="%d" fill="%s"/>`+"\n", labelWidth, y, w, barHeight, engineColor(label))
+		svgText(&b, labelWidth+w+4, y+barHeight-4, "start", formatNumber(values[i]))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// logRange returns the decades covering every positive value.
+func logRange(series []chartSeries) (lo, hi float64, ok bool) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, v := range s.Values {
+			if v > 0 {
+				lo = math.Min(lo, math.Floor(math.Log10(v)))
+				hi = math.Max(hi, math.Ceil(math.Log10(v)))
+			}
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return 0, 0, false
+	}
+	if hi == lo {
+		hi++
+	}
+	return lo, hi, true
+}
+
+// lineChart draws one line per series over xs, labelled xLabel, with both
+// axes on a log scale so that linear growth is a straight line of slope one.
+func lineChart(title, unit, xLabel string, xs []float64, series []chartSeries) string {
+	const width, height, left, right, top, bottom = 480, 260, 60, 90, 28, 36
+	plotW, plotH := float64(width-left-right), float64(height-top-bottom)
+	ylo, yhi, ok := logRange(series)
+	xlo, xhi, _ := logRange([]chartSeries{{Values: xs}})
+	var b strings.Builder
+	svgOpen(&b, width, height)
+	fmt.Fprintf(&b, `<text x="4" y="16" font-weight="bold">%s</text>`+"\n", html.EscapeString(title+" ("+unit+")"))
+	if !ok {
+		svgText(&b, width/2, height/2, "middle", "no data")
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+	px := func(x float64) float64 { return left + (math.Log10(x)-xlo)/(xhi-xlo)*plotW }
+	py := func(y float64) float64 { return top + plotH - (math.Log10(y)-ylo)/(yhi-ylo)*plotH }
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#ccc"/>`+"\n", left, top, plotW, plotH)
+	for d := ylo; d <= yhi; d++ {
+		y := py(math.Pow(10, d))
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#eee"/>`+"\n", left, y, left+plotW, y)
+		svgText(&b, left-4, y+4, "end", formatTick(math.Pow(10, d)))
+	}
+	if len(xs) <= 8 {
+		for _, x := range xs {
+			svgText(&b, px(x), top+plotH+14, "middle", formatNumber(x))
+		}
+	} else {
+		for d := xlo; d <= xhi; d++ {
+			svgText(&b, px(math.Pow(10, d)), top+plotH+14, "middle", formatTick(math.Pow(10, d)))
+		}
+	}
+	svgText(&b, left+plotW/2, top+plotH+30, "middle", xLabel)
+	for i, s := range series {
+		var points []string
+		for j, v := range s.Values {
+			if v > 0 && j < len(xs) {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", px(xs[j]), py(v)))
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"/>`+"\n", px(xs[j]), py(v), s.color())
+			}
+		}
+		if len(points) > 1 {
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", strings.Join(points, " "), s.color())
+		}
+		y := float64(top + 8 + i*16)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`+"\n", left+plotW+8, y-9, s.color())
+		svgText(&b, left+plotW+22, y, "start", s.Name)
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// groupedBarChart draws one group of horizontal bars per category, one bar
+// per series. With logScale bar lengths are proportional to the decades
+// above the smallest value, which keeps engines an order of magnitude
+// apart readable on the same chart.
+func groupedBarChart(title, unit string, categories []string, series []chartSeries, logScale bool) string {
+	const width, labelWidth, right, barHeight, groupGap, top = 640, 150, 60, 10, 10, 44
+	plot := float64(width - labelWidth - right)
+	groupHeight := len(series)*barHeight + groupGap
+	height := top + len(categories)*groupHeight + 24
+	var max float64
+	for _, s := range series {
+		for _, v := range s.Values {
+			max = math.Max(max, v)
+		}
+	}
+	lo, hi, ok := logRange(series)
+	length := func(v float64) float64 { return v / max * plot }
+	if logScale {
+		// Start one decade below the smallest value so it still gets a bar.
+		lo--
+		length = func(v float64) float64 { return (math.Log10(v) - lo) / (hi - lo) * plot }
+	}
+
+	var b strings.Builder
+	svgOpen(&b, width, height)
+	scale := ""
+	if logScale {
+		scale = ", log scale"
+	}
+	fmt.Fprintf(&b, `<text x="4" y="16" font-weight="bold">%s</text>`+"\n", html.EscapeString(title+" ("+unit+scale+")"))
+	for i, s := range series {
+		x := float64(labelWidth + i*90)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="24" width="10" height="10" fill="%s"/>`+"\n", x, s.color())
+		svgText(&b, x+14, 33, "start", s.Name)
+	}
+	if !ok {
+		svgText(&b, width/2, float64(height)/2, "middle", "no data")
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+	bottom := float64(height - 24)
+	if logScale {
+		for d := lo; d <= hi; d++ {
+			x := labelWidth + length(math.Pow(10, d))
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#eee"/>`+"\n", x, top, x, bottom)
+			svgText(&b, x, bottom+14, "middle", formatTick(math.Pow(10, d)))
+		}
+	}
+	for i, category := range categories {
+		y := float64(top + i*groupHeight)
+		svgText(&b, labelWidth-6, y+float64(len(series)*barHeight)/2+4, "end", category)
+		for j, s := range series {
+			v := s.Values[i]
+			if v <= 0 {
+				continue
+			}
+			by := y + float64(j*barHeight)
+			w := math.Max(1, length(v))
+			fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%.1f" height="%d" fill="%s"/>`+"\n", labelWidth, by, w, barHeight-1, s.color())
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="9">%s</text>`+"\n", labelWidth+w+3, by+barHeight-2, formatNumber(v))
+		}
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%.1f" stroke="#999"/>`+"\n", labelWidth, top, labelWidth, bottom)
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// engineChart is the chart of engineTable in op/ms: one group per row,
+// one bar per engine, higher is better.
+func engineChart(summaries []summary, logScale bool) string {
+	p := newEnginePivot(summaries)
+	series := make([]chartSeries, len(p.engines))
+	for j, e := range p.engines {
+		series[j] = chartSeries{Name: e, Values: make([]float64, len(p.rows))}
+		for i := range p.rows {
+			series[j].Values[i] = opsPerMs(p.ns[i][j])
+		}
+	}
+	return groupedBarChart("Throughput", "op/ms", p.rows, series, logScale)
+}
+
+// trendChart plots values over the categorical labels, such as releases,
+// on a linear axis from zero. Points whose change is at least threshold
+// percent are marked green for improvements and red for regressions.
+func trendChart(title, unit string, labels []string, values, changes []float64, threshold float64) string {
+	const width, height, left, right, top, bottom = 560, 240, 70, 20, 28, 44
+	plotW, plotH := float64(width-left-right), float64(height-top-bottom)
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	var b strings.Builder
+	svgOpen(&b, width, height)
+	fmt.Fprintf(&b, `<text x="4" y="16" font-weight="bold">%s</text>`+"\n", html.EscapeString(title+" ("+unit+")"))
+	if max == 0 {
+		svgText(&b, width/2, height/2, "middle", "no data")
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+	max *= 1.1
+	step := plotW
+	if len(labels) > 1 {
+		step = plotW / float64(len(labels)-1)
+	}
+	px := func(i int) float64 {
+		if len(labels) == 1 {
+			return left + plotW/2
+		}
+		return left + float64(i)*step
+	}
+	py := func(v float64) float64 { return top + plotH - v/max*plotH }
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#ccc"/>`+"\n", left, top, plotW, plotH)
+	for k := 0; k <= 4; k++ {
+		v := max * float64(k) / 4
+		svgText(&b, left-4, py(v)+4, "end", formatTick(math.Round(v)))
+	}
+	var points []string
+	for i, label := range labels {
+		svgText(&b, px(i), top+plotH+14, "middle", label)
+		if values[i] > 0 {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(i), py(values[i])))
+		}
+	}
+	if len(points) > 1 {
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", strings.Join(points, " "), engineColor("glisp"))
+	}
+	for i, v := range values {
+		if v <= 0 {
+			continue
+		}
+		color := engineColor("glisp")
+		switch {
+		case changes[i] <= -threshold:
+			color = "#2ca02c"
+		case changes[i] >= threshold:
+			color = "#d62728"
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3.5" fill="%s"/>`+"\n", px(i), py(v), color)
+		if color != engineColor("glisp") {
+			svgText(&b, px(i), py(v)-8, "middle", fmt.Sprintf("%+.1f%%", changes[i]))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
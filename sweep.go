@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gcSetting is one point of a GC sweep, as the values of the GOGC and
+// GOMEMLIMIT environment variables.
+type gcSetting struct {
+	GOGC       string `json:"gogc"`
+	GOMEMLIMIT string `json:"gomemlimit"`
+}
+
+func (s gcSetting) String() string {
+	if s.GOMEMLIMIT == "off" {
+		return "GOGC=" + s.GOGC
+	}
+	return "GOGC=" + s.GOGC + " GOMEMLIMIT=" + s.GOMEMLIMIT
+}
+
+var memLimitPattern = regexp.MustCompile(`^\d+(B|KiB|MiB|GiB|TiB)?$`)
+
+// gcSettings is every combination of the comma separated GOGC and
+// GOMEMLIMIT values, in the syntax the runtime accepts.
+func gcSettings(gogc, memLimits string) ([]gcSetting, error) {
+	var settings []gcSetting
+	for _, g := range parseVersions(gogc) {
+		if n, err := strconv.Atoi(g); g != "off" && (err != nil || n < 0) {
+			return nil, fmt.Errorf("invalid GOGC value %q", g)
+		}
+		for _, m := range parseVersions(memLimits) {
+			if m != "off" && !memLimitPattern.MatchString(m) {
+				return nil, fmt.Errorf("invalid GOMEMLIMIT value %q", m)
+			}
+			settings = append(settings, gcSetting{GOGC: g, GOMEMLIMIT: m})
+		}
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no GOGC or GOMEMLIMIT values")
+	}
+	return settings, nil
+}
+
+// sweepRun is the run of one setting, or the error of its subprocess.
+type sweepRun struct {
+	Setting gcSetting `json:"setting"`
+	Run     *benchRun `json:"run,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// runSetting runs the benchmarks in a subprocess of this binary with the
+// environment of setting, since the runtime only reads GOGC and GOMEMLIMIT
+// at startup.
+func runSetting(setting gcSetting, args []string) sweepRun {
+	res := sweepRun{Setting: setting}
+	exe, err := os.Executable()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	dir, err := os.MkdirTemp("", "glisp-sweep-")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "run.json")
+	cmd := exec.Command(exe, append([]string{"run", "-q", "-dir", "", "-format", "json", "-o", out}, args...)...)
+	cmd.Env = append(os.Environ(), "GOGC="+setting.GOGC, "GOMEMLIMIT="+setting.GOMEMLIMIT)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		res.Error = fmt.Sprintf("%v\n%s", err, stderr.String())
+		return res
+	}
+	if res.Run, err = loadRun(out); err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// sweepMatrix holds op/ms and GC cycles per op of every benchmark (rows,
+// workload_engine) under every setting (columns). Missing cells are NaN.
+type sweepMatrix struct {
+	settings []gcSetting
+	rows     []string
+	engines  []string
+	opsPerMs [][]float64
+	gcPerOp  [][]float64
+	// baseline is the column of the runtime defaults, GOGC=100 without a
+	// memory limit, or the first column when the sweep leaves them out.
+	baseline int
+}
+
+func newSweepMatrix(runs []sweepRun) *sweepMatrix {
+	m := &sweepMatrix{}
+	index := make(map[string]int)
+	engines := make(map[string]bool)
+	for j, r := range runs {
+		m.settings = append(m.settings, r.Setting)
+		if r.Setting == (gcSetting{GOGC: "100", GOMEMLIMIT: "off"}) {
+			m.baseline = j
+		}
+		if r.Run == nil {
+			continue
+		}
+		for _, s := range summarize(r.Run.Results) {
+			name := s.Workload + "_" + s.Engine
+			i, ok := index[name]
+			if !ok {
+				i = len(m.rows)
+				index[name] = i
+				m.rows = append(m.rows, name)
+				m.opsPerMs = append(m.opsPerMs, nanRow(len(runs)))
+				m.gcPerOp = append(m.gcPerOp, nanRow(len(runs)))
+				if !engines[s.Engine] {
+					engines[s.Engine] = true
+					m.engines = append(m.engines, s.Engine)
+				}
+			}
+			if s.Failed {
+				continue
+			}
+			m.opsPerMs[i][j] = opsPerMs(s.NsPerOp)
+			if v, ok := s.Runtime["gc-cycles/op"]; ok {
+				m.gcPerOp[i][j] = v
+			}
+		}
+	}
+	return m
+}
+
+func nanRow(n int) []float64 {
+	row := make([]float64, n)
+	for i := range row {
+		row[i] = math.NaN()
+	}
+	return row
+}
+
+func (m *sweepMatrix) header(first string) []string {
+	header := []string{first}
+	for _, s := range m.settings {
+		header = append(header, s.String())
+	}
+	return header
+}
+
+// table lists one value per cell, formatted by format, with the best
+// setting of every row in bold when higher (or lower) is better.
+func (m *sweepMatrix) table(values [][]float64, format func(float64) string, higherIsBetter bool) *textTable {
+	t := &textTable{header: m.header("Benchmark"), bold: make(map[[2]int]bool)}
+	for i, name := range m.rows {
+		row := []string{name}
+		best := -1
+		for j, v := range values[i] {
+			if math.IsNaN(v) {
+				row = append(row, "N/A")
+				continue
+			}
+			row = append(row, format(v))
+			if best < 0 || higherIsBetter && v > values[i][best] || !higherIsBetter && v < values[i][best] {
+				best = j
+			}
+		}
+		if best >= 0 {
+			t.bold[[2]int{i, best + 1}] = true
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// engineGains is the geometric mean over the workloads of every engine of
+// the op/ms change against the baseline column, in percent. It answers
+// which engine benefits most from GC tuning.
+func (m *sweepMatrix) engineGains() [][]float64 {
+	gains := make([][]float64, len(m.engines))
+	for e, engine := range m.engines {
+		gains[e] = nanRow(len(m.settings))
+		for j := range m.settings {
+			sum, n := 0.0, 0
+			for i, name := range m.rows {
+				base, v := m.opsPerMs[i][m.baseline], m.opsPerMs[i][j]
+				if !strings.HasSuffix(name, "_"+engine) || !(base > 0) || !(v > 0) {
+					continue
+				}
+				sum += math.Log(v / base)
+				n++
+			}
+			if n > 0 {
+				gains[e][j] = (math.Exp(sum/float64(n)) - 1) * 100
+			}
+		}
+	}
+	return gains
+}
+
+// sweepTables are the op/ms matrix, the GC matrix in cycles per thousand
+// ops, and the mean change of every engine against the baseline.
+func sweepTables(m *sweepMatrix) []*textTable {
+	ops := m.table(m.opsPerMs, formatNumber, true)
+	gcPerKop := make([][]float64, len(m.gcPerOp))
+	for i, row := range m.gcPerOp {
+		gcPerKop[i] = make([]float64, len(row))
+		for j, v := range row {
+			gcPerKop[i][j] = v * 1000
+		}
+	}
+	gcs := m.table(gcPerKop, func(v float64) string { return strconv.FormatFloat(v, 'g', 3, 64) }, false)
+	ops.header[0] = "Benchmark (op/ms)"
+	gcs.header[0] = "Benchmark (GCs per 1000 op)"
+	gains := m.engineGains()
+	t := &textTable{header: m.header("Engine (op/ms vs " + m.settings[m.baseline].String() + ")"), bold: make(map[[2]int]bool)}
+	for e, engine := range m.engines {
+		row := []string{engine}
+		best := -1
+		for j, g := range gains[e] {
+			if math.IsNaN(g) {
+				row = append(row, "N/A")
+				continue
+			}
+			row = append(row, fmt.Sprintf("%+.1f%%", g))
+			if j != m.baseline && (best < 0 || g > gains[e][best]) {
+				best = j
+			}
+		}
+		if best >= 0 && gains[e][best] > 0 {
+			t.bold[[2]int{e, best + 1}] = true
+		}
+		t.rows = append(t.rows, row)
+	}
+	return []*textTable{ops, gcs, t}
+}
@@ -0,0 +1,15 @@
+function classify_order(amount, country, vip) {
+	if (amount <= 0) {
+		return "reject";
+	}
+	if (amount > 1000 && !vip) {
+		return "review";
+	}
+	if (country !== "US" && country !== "DE" && country !== "FR") {
+		return "review";
+	}
+	if (amount > 5000) {
+		return "review";
+	}
+	return "approve";
+}
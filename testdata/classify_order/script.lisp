@@ -0,0 +1,6 @@
+(defn classify_order [amount country vip]
+  (cond (<= amount 0) "reject"
+        (and (> amount 1000) (not vip)) "review"
+        (not (or (= country "US") (= country "DE") (= country "FR"))) "review"
+        (> amount 5000) "review"
+        "approve"))
@@ -0,0 +1,15 @@
+function classify_order(amount, country, vip)
+  if amount <= 0 then
+    return "reject"
+  end
+  if amount > 1000 and not vip then
+    return "review"
+  end
+  if country ~= "US" and country ~= "DE" and country ~= "FR" then
+    return "review"
+  end
+  if amount > 5000 then
+    return "review"
+  end
+  return "approve"
+end
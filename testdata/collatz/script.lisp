@@ -0,0 +1,5 @@
+(defn collatz-loop [n steps]
+  (cond (= n 1) steps
+        (collatz-loop (cond (= 0 (mod n 2)) (/ n 2) (+ (* 3 n) 1)) (+ steps 1))))
+
+(defn collatz [n] (collatz-loop n 0))
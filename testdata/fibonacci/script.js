@@ -0,0 +1,9 @@
+function fibonacci(n) {
+	let a = 0, b = 1;
+	for (let i = 0; i < n; i++) {
+		const t = a + b;
+		a = b;
+		b = t;
+	}
+	return a;
+}
@@ -0,0 +1,5 @@
+(defn fib-loop [a b n]
+  (cond (= n 0) a
+        (fib-loop b (+ a b) (- n 1))))
+
+(defn fibonacci [n] (fib-loop 0 1 n))
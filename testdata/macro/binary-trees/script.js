@@ -0,0 +1,27 @@
+function bottom_up(depth) {
+	if (depth === 0) {
+		return [null, null];
+	}
+	return [bottom_up(depth - 1), bottom_up(depth - 1)];
+}
+
+function check(node) {
+	if (node[0] === null) {
+		return 1;
+	}
+	return 1 + check(node[0]) + check(node[1]);
+}
+
+function binary_trees(n) {
+	const minDepth = 4;
+	const maxDepth = Math.max(minDepth + 2, n);
+	let total = check(bottom_up(maxDepth + 1));
+	const longLived = bottom_up(maxDepth);
+	for (let depth = minDepth; depth <= maxDepth; depth += 2) {
+		const iterations = 1 << (maxDepth - depth + minDepth);
+		for (let i = 0; i < iterations; i++) {
+			total += check(bottom_up(depth));
+		}
+	}
+	return total + check(longLived);
+}
@@ -0,0 +1,23 @@
+(defn bottom-up [depth]
+  (cond (= depth 0) (cons nil nil)
+        (cons (bottom-up (- depth 1)) (bottom-up (- depth 1)))))
+
+(defn check [node]
+  (cond (nil? (car node)) 1
+        (+ 1 (check (car node)) (check (cdr node)))))
+
+(defn iterate [depth i n total]
+  (cond (= i n) total
+        (iterate depth (+ i 1) n (+ total (check (bottom-up depth))))))
+
+(defn depths [depth min-depth max-depth total]
+  (cond (> depth max-depth) total
+        (depths (+ depth 2) min-depth max-depth
+                (iterate depth 0 (sla 1 (+ (- max-depth depth) min-depth)) total))))
+
+(defn binary_trees [n]
+  (def min-depth 4)
+  (def max-depth (max (+ min-depth 2) n))
+  (def total (check (bottom-up (+ max-depth 1))))
+  (def long-lived (bottom-up max-depth))
+  (+ (depths min-depth min-depth max-depth total) (check long-lived)))
@@ -0,0 +1,27 @@
+local function bottom_up(depth)
+  if depth == 0 then
+    return {}
+  end
+  return {bottom_up(depth - 1), bottom_up(depth - 1)}
+end
+
+local function check(node)
+  if node[1] == nil then
+    return 1
+  end
+  return 1 + check(node[1]) + check(node[2])
+end
+
+function binary_trees(n)
+  local min_depth = 4
+  local max_depth = math.max(min_depth + 2, n)
+  local total = check(bottom_up(max_depth + 1))
+  local long_lived = bottom_up(max_depth)
+  for depth = min_depth, max_depth, 2 do
+    local iterations = 2 ^ (max_depth - depth + min_depth)
+    for i = 1, iterations do
+      total = total + check(bottom_up(depth))
+    end
+  end
+  return total + check(long_lived)
+end
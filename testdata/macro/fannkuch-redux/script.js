@@ -0,0 +1,42 @@
+function fannkuch(n) {
+	const perm1 = [], perm = new Array(n).fill(0), count = new Array(n).fill(0);
+	for (let i = 0; i < n; i++) {
+		perm1.push(i);
+	}
+	let maxFlips = 0, checksum = 0, permCount = 0, r = n;
+	for (;;) {
+		for (; r !== 1; r--) {
+			count[r - 1] = r;
+		}
+		for (let i = 0; i < n; i++) {
+			perm[i] = perm1[i];
+		}
+		let flips = 0;
+		for (let k = perm[0]; k !== 0; k = perm[0]) {
+			for (let i = 0, j = k; i < j; i++, j--) {
+				const t = perm[i];
+				perm[i] = perm[j];
+				perm[j] = t;
+			}
+			flips++;
+		}
+		maxFlips = Math.max(maxFlips, flips);
+		checksum += permCount % 2 === 0 ? flips : -flips;
+		for (;;) {
+			if (r === n) {
+				return checksum + " " + maxFlips;
+			}
+			const p0 = perm1[0];
+			for (let i = 0; i < r; i++) {
+				perm1[i] = perm1[i + 1];
+			}
+			perm1[r] = p0;
+			count[r]--;
+			if (count[r] > 0) {
+				break;
+			}
+			r++;
+		}
+		permCount++;
+	}
+}
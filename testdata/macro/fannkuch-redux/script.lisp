@@ -0,0 +1,55 @@
+(defn flip! [perm i j]
+  (when (< i j)
+    (def t (aget perm i))
+    (aset! perm i (aget perm j))
+    (aset! perm j t)
+    (flip! perm (+ i 1) (- j 1))))
+
+(defn count-flips [perm flips]
+  (def k (aget perm 0))
+  (cond (= k 0) flips
+        (begin (flip! perm 0 k) (count-flips perm (+ flips 1)))))
+
+(defn copy! [dst src i]
+  (when (< i (len src))
+    (aset! dst i (aget src i))
+    (copy! dst src (+ i 1))))
+
+;; reset-count! sets count[r-1] = r down to r = 2 and returns 1.
+(defn reset-count! [count r]
+  (cond (= r 1) r
+        (begin (aset! count (- r 1) r) (reset-count! count (- r 1)))))
+
+(defn shift! [perm1 i r]
+  (when (< i r)
+    (aset! perm1 i (aget perm1 (+ i 1)))
+    (shift! perm1 (+ i 1) r)))
+
+;; next-perm! rotates perm1 to the next permutation and returns the new r,
+;; or n when every permutation has been visited.
+(defn next-perm! [perm1 count r n]
+  (cond (= r n) n
+        (begin
+          (def p0 (aget perm1 0))
+          (shift! perm1 0 r)
+          (aset! perm1 r p0)
+          (aset! count r (- (aget count r) 1))
+          (cond (> (aget count r) 0) r
+                (next-perm! perm1 count (+ r 1) n)))))
+
+(defn fannkuch-from [n perm1 perm count r perm-count checksum max-flips]
+  (def r (reset-count! count r))
+  (copy! perm perm1 0)
+  (def flips (count-flips perm 0))
+  (def checksum (cond (= 0 (mod perm-count 2)) (+ checksum flips) (- checksum flips)))
+  (def max-flips (cond (> flips max-flips) flips max-flips))
+  (def r (next-perm! perm1 count r n))
+  (cond (= r n) (concat (string checksum) " " (string max-flips))
+        (fannkuch-from n perm1 perm count r (+ perm-count 1) checksum max-flips)))
+
+(defn identity-array [arr i]
+  (cond (= i (len arr)) arr
+        (begin (aset! arr i i) (identity-array arr (+ i 1)))))
+
+(defn fannkuch [n]
+  (fannkuch-from n (identity-array (make-array n 0) 0) (make-array n 0) (make-array n 0) n 0 0 0))
@@ -0,0 +1,74 @@
+const ALU =
+	"GGCCGGGCGCGGTGGCTCACGCCTGTAATCCCAGCACTTTGG" +
+	"GAGGCCGAGGCGGGCGGATCACCTGAGGTCAGGAGTTCGAGA" +
+	"CCAGCCTGGCCAACATGGTGAAACCCCGTCTCTACTAAAAAT" +
+	"ACAAAAATTAGCCGGGCGTGGTGGCGCGCGCCTGTAATCCCA" +
+	"GCTACTCGGGAGGCTGAGGCAGGAGAATCGCTTGAACCCGGG" +
+	"AGGCGGAGGTTGCAGTGAGCCGAGATCGCGCCACTGCACTCC" +
+	"AGCCTGGGCGACAGAGCGAGACTCCGTCTCAAAAA";
+
+const IUB_CHARS = "acgtBDHKMNRSVWY";
+const IUB_PROBS = [0.27, 0.12, 0.12, 0.27, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02];
+const HOMO_CHARS = "acgt";
+const HOMO_PROBS = [0.3029549426680, 0.1979883004921, 0.1975473066391, 0.3015094502008];
+
+let seed = 42;
+
+function random() {
+	seed = (seed * 3877 + 29573) % 139968;
+	return seed / 139968;
+}
+
+function repeat(out, s, n) {
+	let k = 0;
+	while (n > 0) {
+		const m = Math.min(n, 60);
+		let line = "";
+		for (let i = 0; i < m; i++) {
+			line += s[k];
+			k = (k + 1) % s.length;
+		}
+		out.push(line);
+		n -= m;
+	}
+}
+
+function random_seq(out, chars, probs, n) {
+	const cum = [];
+	let acc = 0;
+	for (const p of probs) {
+		acc += p;
+		cum.push(acc);
+	}
+	while (n > 0) {
+		const m = Math.min(n, 60);
+		let line = "";
+		for (let i = 0; i < m; i++) {
+			const r = random();
+			let j = 0;
+			while (j < cum.length - 1 && r >= cum[j]) {
+				j++;
+			}
+			line += chars[j];
+		}
+		out.push(line);
+		n -= m;
+	}
+}
+
+// fasta returns the length of the output, counting newlines, and its last
+// line.
+function fasta(n) {
+	seed = 42;
+	const out = [">ONE Homo sapiens alu"];
+	repeat(out, ALU, 2 * n);
+	out.push(">TWO IUB ambiguity codes");
+	random_seq(out, IUB_CHARS, IUB_PROBS, 3 * n);
+	out.push(">THREE Homo sapiens frequency");
+	random_seq(out, HOMO_CHARS, HOMO_PROBS, 5 * n);
+	let total = 0;
+	for (const line of out) {
+		total += line.length + 1;
+	}
+	return total + " " + out[out.length - 1];
+}
@@ -0,0 +1,56 @@
+(def alu (concat
+  "GGCCGGGCGCGGTGGCTCACGCCTGTAATCCCAGCACTTTGG"
+  "GAGGCCGAGGCGGGCGGATCACCTGAGGTCAGGAGTTCGAGA"
+  "CCAGCCTGGCCAACATGGTGAAACCCCGTCTCTACTAAAAAT"
+  "ACAAAAATTAGCCGGGCGTGGTGGCGCGCGCCTGTAATCCCA"
+  "GCTACTCGGGAGGCTGAGGCAGGAGAATCGCTTGAACCCGGG"
+  "AGGCGGAGGTTGCAGTGAGCCGAGATCGCGCCACTGCACTCC"
+  "AGCCTGGGCGACAGAGCGAGACTCCGTCTCAAAAA"))
+
+(def iub-chars "acgtBDHKMNRSVWY")
+(def iub-probs [0.27 0.12 0.12 0.27 0.02 0.02 0.02 0.02 0.02 0.02 0.02 0.02 0.02 0.02 0.02])
+(def homo-chars "acgt")
+(def homo-probs [0.3029549426680 0.1979883004921 0.1975473066391 0.3015094502008])
+
+(def seed 42)
+
+(defn random []
+  (set! seed (mod (+ (* seed 3877) 29573) 139968))
+  (/ (float seed) 139968.0))
+
+(defn repeat-line [s k i m line]
+  (cond (= i m) line
+        (repeat-line s (mod (+ k 1) (len s)) (+ i 1) m (append line (sget s k)))))
+
+(defn repeat-seq [out s k n]
+  (cond (<= n 0) out
+        (begin
+          (def m (min n 60))
+          (repeat-seq (append out (repeat-line s k 0 m "")) s (mod (+ k m) (len s)) (- n m)))))
+
+(defn pick [cum r j]
+  (cond (and (< j (- (len cum) 1)) (>= r (aget cum j))) (pick cum r (+ j 1))
+        j))
+
+(defn random-line [chars cum i m line]
+  (cond (= i m) line
+        (random-line chars cum (+ i 1) m (append line (sget chars (pick cum (random) 0))))))
+
+(defn random-seq [out chars cum n]
+  (cond (<= n 0) out
+        (begin
+          (def m (min n 60))
+          (random-seq (append out (random-line chars cum 0 m "")) chars cum (- n m)))))
+
+(defn cumulative [probs]
+  (foldl (fn [p acc] (append acc (+ p (cond (empty? acc) 0.0 (aget acc (- (len acc) 1)))))) [] probs))
+
+;; fasta returns the length of the output, counting newlines, and its last
+;; line.
+(defn fasta [n]
+  (set! seed 42)
+  (def out (repeat-seq [">ONE Homo sapiens alu"] alu 0 (* 2 n)))
+  (def out (random-seq (append out ">TWO IUB ambiguity codes") iub-chars (cumulative iub-probs) (* 3 n)))
+  (def out (random-seq (append out ">THREE Homo sapiens frequency") homo-chars (cumulative homo-probs) (* 5 n)))
+  (def total (foldl (fn [line acc] (+ acc (len line) 1)) 0 out))
+  (concat (string total) " " (aget out (- (len out) 1))))
@@ -0,0 +1,73 @@
+local ALU =
+  "GGCCGGGCGCGGTGGCTCACGCCTGTAATCCCAGCACTTTGG" ..
+  "GAGGCCGAGGCGGGCGGATCACCTGAGGTCAGGAGTTCGAGA" ..
+  "CCAGCCTGGCCAACATGGTGAAACCCCGTCTCTACTAAAAAT" ..
+  "ACAAAAATTAGCCGGGCGTGGTGGCGCGCGCCTGTAATCCCA" ..
+  "GCTACTCGGGAGGCTGAGGCAGGAGAATCGCTTGAACCCGGG" ..
+  "AGGCGGAGGTTGCAGTGAGCCGAGATCGCGCCACTGCACTCC" ..
+  "AGCCTGGGCGACAGAGCGAGACTCCGTCTCAAAAA"
+
+local IUB_CHARS = "acgtBDHKMNRSVWY"
+local IUB_PROBS = {0.27, 0.12, 0.12, 0.27, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02, 0.02}
+local HOMO_CHARS = "acgt"
+local HOMO_PROBS = {0.3029549426680, 0.1979883004921, 0.1975473066391, 0.3015094502008}
+
+local seed = 42
+
+local function random()
+  seed = (seed * 3877 + 29573) % 139968
+  return seed / 139968
+end
+
+local function repeat_seq(out, s, n)
+  local k = 0
+  while n > 0 do
+    local m = math.min(n, 60)
+    local line = ""
+    for i = 1, m do
+      line = line .. string.sub(s, k + 1, k + 1)
+      k = (k + 1) % #s
+    end
+    out[#out + 1] = line
+    n = n - m
+  end
+end
+
+local function random_seq(out, chars, probs, n)
+  local cum, acc = {}, 0
+  for i = 1, #probs do
+    acc = acc + probs[i]
+    cum[i] = acc
+  end
+  while n > 0 do
+    local m = math.min(n, 60)
+    local line = ""
+    for i = 1, m do
+      local r = random()
+      local j = 1
+      while j < #cum and r >= cum[j] do
+        j = j + 1
+      end
+      line = line .. string.sub(chars, j, j)
+    end
+    out[#out + 1] = line
+    n = n - m
+  end
+end
+
+-- fasta returns the length of the output, counting newlines, and its last
+-- line.
+function fasta(n)
+  seed = 42
+  local out = {">ONE Homo sapiens alu"}
+  repeat_seq(out, ALU, 2 * n)
+  out[#out + 1] = ">TWO IUB ambiguity codes"
+  random_seq(out, IUB_CHARS, IUB_PROBS, 3 * n)
+  out[#out + 1] = ">THREE Homo sapiens frequency"
+  random_seq(out, HOMO_CHARS, HOMO_PROBS, 5 * n)
+  local total = 0
+  for i = 1, #out do
+    total = total + #out[i] + 1
+  end
+  return string.format("%d %s", total, out[#out])
+end
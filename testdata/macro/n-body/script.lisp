@@ -0,0 +1,85 @@
+(def solar-mass (* 4 3.141592653589793 3.141592653589793))
+(def days-per-year 365.24)
+
+;; A body is [x y z vx vy vz mass].
+(defn make-bodies []
+  [[0.0 0.0 0.0 0.0 0.0 0.0 solar-mass]
+   [4.84143144246472090e+00 -1.16032004402742839e+00 -1.03622044471123109e-01
+    (* 1.66007664274403694e-03 days-per-year) (* 7.69901118419740425e-03 days-per-year)
+    (* -6.90460016972063023e-05 days-per-year) (* 9.54791938424326609e-04 solar-mass)]
+   [8.34336671824457987e+00 4.12479856412430479e+00 -4.03523417114321381e-01
+    (* -2.76742510726862411e-03 days-per-year) (* 4.99852801234917238e-03 days-per-year)
+    (* 2.30417297573763929e-05 days-per-year) (* 2.85885980666130812e-04 solar-mass)]
+   [1.28943695621391310e+01 -1.51111514016986312e+01 -2.23307578892655734e-01
+    (* 2.96460137564761618e-03 days-per-year) (* 2.37847173959480950e-03 days-per-year)
+    (* -2.96589568540237556e-05 days-per-year) (* 4.36624404335156298e-05 solar-mass)]
+   [1.53796971148509165e+01 -2.59193146099879641e+01 1.79258772950371181e-01
+    (* 2.68067772490389322e-03 days-per-year) (* 1.62824170038242295e-03 days-per-year)
+    (* -9.51592254519715870e-05 days-per-year) (* 5.15138902046611451e-05 solar-mass)]])
+
+(defn potential [bodies b j e]
+  (cond (= j (len bodies)) e
+        (begin
+          (def b2 (aget bodies j))
+          (def dx (- (aget b 0) (aget b2 0)))
+          (def dy (- (aget b 1) (aget b2 1)))
+          (def dz (- (aget b 2) (aget b2 2)))
+          (potential bodies b (+ j 1)
+                     (- e (/ (* (aget b 6) (aget b2 6)) (sqrt (+ (* dx dx) (* dy dy) (* dz dz)))))))))
+
+(defn energy-from [bodies i e]
+  (cond (= i (len bodies)) e
+        (begin
+          (def b (aget bodies i))
+          (def kinetic (* 0.5 (aget b 6) (+ (* (aget b 3) (aget b 3)) (* (aget b 4) (aget b 4)) (* (aget b 5) (aget b 5)))))
+          (energy-from bodies (+ i 1) (potential bodies b (+ i 1) (+ e kinetic))))))
+
+(defn energy [bodies] (energy-from bodies 0 0.0))
+
+(defn pull [bodies b j dt]
+  (when (< j (len bodies))
+    (def b2 (aget bodies j))
+    (def dx (- (aget b 0) (aget b2 0)))
+    (def dy (- (aget b 1) (aget b2 1)))
+    (def dz (- (aget b 2) (aget b2 2)))
+    (def d2 (+ (* dx dx) (* dy dy) (* dz dz)))
+    (def mag (/ dt (* d2 (sqrt d2))))
+    (aset! b 3 (- (aget b 3) (* dx (aget b2 6) mag)))
+    (aset! b 4 (- (aget b 4) (* dy (aget b2 6) mag)))
+    (aset! b 5 (- (aget b 5) (* dz (aget b2 6) mag)))
+    (aset! b2 3 (+ (aget b2 3) (* dx (aget b 6) mag)))
+    (aset! b2 4 (+ (aget b2 4) (* dy (aget b 6) mag)))
+    (aset! b2 5 (+ (aget b2 5) (* dz (aget b 6) mag)))
+    (pull bodies b (+ j 1) dt)))
+
+(defn advance-from [bodies i dt]
+  (when (< i (len bodies))
+    (pull bodies (aget bodies i) (+ i 1) dt)
+    (advance-from bodies (+ i 1) dt)))
+
+(defn move [b dt]
+  (aset! b 0 (+ (aget b 0) (* dt (aget b 3))))
+  (aset! b 1 (+ (aget b 1) (* dt (aget b 4))))
+  (aset! b 2 (+ (aget b 2) (* dt (aget b 5)))))
+
+(defn advance [bodies dt]
+  (advance-from bodies 0 dt)
+  (foldl (fn [b acc] (move b dt)) nil bodies))
+
+(defn steps [bodies i n]
+  (when (< i n)
+    (advance bodies 0.01)
+    (steps bodies (+ i 1) n)))
+
+(defn momentum [bodies k]
+  (foldl (fn [b acc] (+ acc (* (aget b k) (aget b 6)))) 0.0 bodies))
+
+(defn nbody [n]
+  (def bodies (make-bodies))
+  (def sun (aget bodies 0))
+  (aset! sun 3 (/ (- 0.0 (momentum bodies 3)) solar-mass))
+  (aset! sun 4 (/ (- 0.0 (momentum bodies 4)) solar-mass))
+  (aset! sun 5 (/ (- 0.0 (momentum bodies 5)) solar-mass))
+  (def before (energy bodies))
+  (steps bodies 0 n)
+  (concat (string before 9) " " (string (energy bodies) 9)))
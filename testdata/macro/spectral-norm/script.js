@@ -0,0 +1,39 @@
+function a(i, j) {
+	return 1 / ((i + j) * (i + j + 1) / 2 + i + 1);
+}
+
+function mul_av(n, v, av) {
+	for (let i = 0; i < n; i++) {
+		let s = 0;
+		for (let j = 0; j < n; j++) {
+			s += a(i, j) * v[j];
+		}
+		av[i] = s;
+	}
+}
+
+function mul_atv(n, v, atv) {
+	for (let i = 0; i < n; i++) {
+		let s = 0;
+		for (let j = 0; j < n; j++) {
+			s += a(j, i) * v[j];
+		}
+		atv[i] = s;
+	}
+}
+
+function spectral_norm(n) {
+	const u = new Array(n).fill(1), v = new Array(n).fill(0), t = new Array(n).fill(0);
+	for (let i = 0; i < 10; i++) {
+		mul_av(n, u, t);
+		mul_atv(n, t, v);
+		mul_av(n, v, t);
+		mul_atv(n, t, u);
+	}
+	let vBv = 0, vv = 0;
+	for (let i = 0; i < n; i++) {
+		vBv += u[i] * v[i];
+		vv += v[i] * v[i];
+	}
+	return Math.sqrt(vBv / vv).toFixed(9);
+}
@@ -0,0 +1,32 @@
+(defn a [i j]
+  (/ 1.0 (+ (/ (* (+ i j) (+ i j 1)) 2) i 1)))
+
+(defn row-sum [n v i j s transpose]
+  (cond (= j n) s
+        (row-sum n v i (+ j 1)
+                 (+ s (* (cond transpose (a j i) (a i j)) (aget v j)))
+                 transpose)))
+
+(defn multiply [n v out i transpose]
+  (when (< i n)
+    (aset! out i (row-sum n v i 0 0.0 transpose))
+    (multiply n v out (+ i 1) transpose)))
+
+(defn power [n u v t k]
+  (when (< k 10)
+    (multiply n u t 0 false)
+    (multiply n t v 0 true)
+    (multiply n v t 0 false)
+    (multiply n t u 0 true)
+    (power n u v t (+ k 1))))
+
+(defn dot [n x y i s]
+  (cond (= i n) s
+        (dot n x y (+ i 1) (+ s (* (aget x i) (aget y i))))))
+
+(defn spectral_norm [n]
+  (def u (make-array n 1.0))
+  (def v (make-array n 0.0))
+  (def t (make-array n 0.0))
+  (power n u v t 0)
+  (string (sqrt (/ (dot n u v 0 0.0) (dot n v v 0 0.0))) 9))
@@ -0,0 +1,42 @@
+local function a(i, j)
+  return 1 / ((i + j) * (i + j + 1) / 2 + i + 1)
+end
+
+local function mul_av(n, v, av)
+  for i = 0, n - 1 do
+    local s = 0
+    for j = 0, n - 1 do
+      s = s + a(i, j) * v[j + 1]
+    end
+    av[i + 1] = s
+  end
+end
+
+local function mul_atv(n, v, atv)
+  for i = 0, n - 1 do
+    local s = 0
+    for j = 0, n - 1 do
+      s = s + a(j, i) * v[j + 1]
+    end
+    atv[i + 1] = s
+  end
+end
+
+function spectral_norm(n)
+  local u, v, t = {}, {}, {}
+  for i = 1, n do
+    u[i], v[i], t[i] = 1, 0, 0
+  end
+  for i = 1, 10 do
+    mul_av(n, u, t)
+    mul_atv(n, t, v)
+    mul_av(n, v, t)
+    mul_atv(n, t, u)
+  end
+  local vBv, vv = 0, 0
+  for i = 1, n do
+    vBv = vBv + u[i] * v[i]
+    vv = vv + v[i] * v[i]
+  end
+  return string.format("%.9f", math.sqrt(vBv / vv))
+end
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// timeWorkload is one date/time operation; every engine defines a script
+// function named fn which must turn the RFC3339 timestamp arg into expect.
+type timeWorkload struct {
+	name   string
+	fn     string
+	arg    string
+	expect string
+}
+
+var timeWorkloads = []timeWorkload{
+	{"tz-convert", "tz_convert", "2024-03-10T08:30:00Z", "2024-03-10 16:30:00"},
+	{"duration", "duration_seconds", "2024-03-10T08:30:00Z", "180930"},
+	{"add-days", "add_days", "2024-01-25T00:00:00Z", "2024-02-04"},
+	{"add-months", "add_months", "2024-01-25T00:00:00Z", "2024-02-25"},
+	{"compare", "compare_times", "2024-03-10T08:30:00Z", "before"},
+	{"epoch", "epoch_roundtrip", "2024-03-10T08:30:00Z", "1710059400 2024-03-10T08:30:00Z"},
+}
+
+func benchTimeOps_glisp(t *testing.B) {
+	vm := glisp.New()
+	ext.ImportCoreUtils(vm)
+	ext.ImportTime(vm)
+	err := vm.SourceStream(bytes.NewBufferString(`
+(def deadline (time/parse "2024-03-12T10:45:30Z"))
+
+(defn tz_convert [s] (time/format (time/parse s) "2006-01-02 15:04:05" "Asia/Shanghai"))
+
+(defn duration_seconds [s] (string (time/sub deadline (time/parse s))))
+
+(defn add_days [s] (time/format (time/add (time/parse s) 10 'day) "2006-01-02"))
+
+(defn add_months [s] (time/format (time/add-date (time/parse s) 0 1 0) "2006-01-02"))
+
+(defn compare_times [s] (cond (< (time/parse s) deadline) "before" "after"))
+
+(defn epoch_roundtrip [s]
+  (let [ts (time/format (time/parse s) 'timestamp)]
+    (concat (string ts) " " (time/format (time/parse ts) "2006-01-02T15:04:05Z07:00" "UTC"))))
+`))
+	MustSuccess(t, err)
+	for _, w := range timeWorkloads {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				v, err := vm.ApplyByName(w.fn, glisp.MakeArgs(glisp.SexpStr(w.arg)))
+				MustSuccess(t, err)
+				s, ok := v.(glisp.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, string(s))
+			}
+		})
+	}
+}
+
+func benchTimeOps_goja(t *testing.B) {
+	const SCRIPT = `
+const deadline = Date.parse("2024-03-12T10:45:30Z");
+
+function pad(n) {
+	return n < 10 ? "0" + n : "" + n;
+}
+
+function ymd(d) {
+	return d.getUTCFullYear() + "-" + pad(d.getUTCMonth() + 1) + "-" + pad(d.getUTCDate());
+}
+
+function duration_seconds(s) {
+	return String((deadline - Date.parse(s)) / 1000);
+}
+
+function add_days(s) {
+	const d = new Date(s);
+	d.setUTCDate(d.getUTCDate() + 10);
+	return ymd(d);
+}
+
+function add_months(s) {
+	const d = new Date(s);
+	d.setUTCMonth(d.getUTCMonth() + 1);
+	return ymd(d);
+}
+
+function compare_times(s) {
+	return Date.parse(s) < deadline ? "before" : "after";
+}
+
+function epoch_roundtrip(s) {
+	const ts = Date.parse(s) / 1000;
+	return ts + " " + new Date(ts * 1000).toISOString().replace(".000Z", "Z");
+}
+`
+	vm := goja.New()
+	_, err := vm.RunString(SCRIPT)
+	MustSuccess(t, err)
+	for _, w := range timeWorkloads {
+		// goja has no time zone data, so tz-convert could only add a fixed
+		// offset, which is not the conversion the other engines time.
+		if w.name == "tz-convert" {
+			continue
+		}
+		f, ok := goja.AssertFunction(vm.Get(w.fn))
+		MustTrue(t, ok)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := f(goja.Undefined(), vm.ToValue(w.arg))
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, res.String())
+			}
+		})
+	}
+}
+
+func benchTimeOps_lua(t *testing.B) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	l.Register("time_parse", func(l *lua.State) int {
+		ts, err := parseUnix(lua.CheckString(l, 1))
+		if err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		l.PushInteger(int(ts))
+		return 1
+	})
+	l.Register("time_format", func(l *lua.State) int {
+		s, err := formatUnix(int64(lua.CheckInteger(l, 1)), lua.CheckString(l, 2), lua.CheckString(l, 3))
+		if err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		l.PushString(s)
+		return 1
+	})
+	l.Register("time_add_date", func(l *lua.State) int {
+		l.PushInteger(int(addDateUnix(int64(lua.CheckInteger(l, 1)), lua.CheckInteger(l, 2), lua.CheckInteger(l, 3), lua.CheckInteger(l, 4))))
+		return 1
+	})
+	script := `
+local deadline = time_parse("2024-03-12T10:45:30Z")
+
+function tz_convert(s)
+  return time_format(time_parse(s), "2006-01-02 15:04:05", "Asia/Shanghai")
+end
+
+function duration_seconds(s)
+  return tostring(deadline - time_parse(s))
+end
+
+function add_days(s)
+  return time_format(time_add_date(time_parse(s), 0, 0, 10), "2006-01-02", "UTC")
+end
+
+function add_months(s)
+  return time_format(time_add_date(time_parse(s), 0, 1, 0), "2006-01-02", "UTC")
+end
+
+function compare_times(s)
+  if time_parse(s) < deadline then
+    return "before"
+  end
+  return "after"
+end
+
+function epoch_roundtrip(s)
+  local ts = time_parse(s)
+  return ts .. " " .. time_format(ts, "2006-01-02T15:04:05Z07:00", "UTC")
+end
+`
+	err := lua.DoString(l, script)
+	MustSuccess(t, err)
+	for _, w := range timeWorkloads {
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				l.Global(w.fn)
+				l.PushString(w.arg)
+				err = l.ProtectedCall(1, 1, 0)
+				MustSuccess(t, err)
+				s, ok := l.ToString(-1)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s)
+				l.Pop(1)
+			}
+		})
+	}
+}
+
+func benchTimeOps_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	env.AddFunction("timeParse", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 1 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		s, ok := args[0].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("argument of %v should be a string", name)
+		}
+		ts, err := parseUnix(s.S)
+		if err != nil {
+			return zygo.SexpNull, err
+		}
+		return &zygo.SexpInt{Val: ts}, nil
+	})
+	env.AddFunction("timeFormat", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 3 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		ts, ok := args[0].(*zygo.SexpInt)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("1st argument of %v should be an int", name)
+		}
+		layout, ok := args[1].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("2nd argument of %v should be a string", name)
+		}
+		tz, ok := args[2].(*zygo.SexpStr)
+		if !ok {
+			return zygo.SexpNull, fmt.Errorf("3rd argument of %v should be a string", name)
+		}
+		s, err := formatUnix(ts.Val, layout.S, tz.S)
+		if err != nil {
+			return zygo.SexpNull, err
+		}
+		return &zygo.SexpStr{S: s}, nil
+	})
+	env.AddFunction("timeAddDate", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 4 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		vals := make([]int64, len(args))
+		for i, arg := range args {
+			n, ok := arg.(*zygo.SexpInt)
+			if !ok {
+				return zygo.SexpNull, fmt.Errorf("argument %d of %v should be an int", i+1, name)
+			}
+			vals[i] = n.Val
+		}
+		return &zygo.SexpInt{Val: addDateUnix(vals[0], int(vals[1]), int(vals[2]), int(vals[3]))}, nil
+	})
+	_, err := env.EvalString(`
+(def deadline (timeParse "2024-03-12T10:45:30Z"))
+
+(defn tz_convert [s] (timeFormat (timeParse s) "2006-01-02 15:04:05" "Asia/Shanghai"))
+
+(defn duration_seconds [s] (sprintf "%d" (- deadline (timeParse s))))
+
+(defn add_days [s] (timeFormat (timeAddDate (timeParse s) 0 0 10) "2006-01-02" "UTC"))
+
+(defn add_months [s] (timeFormat (timeAddDate (timeParse s) 0 1 0) "2006-01-02" "UTC"))
+
+(defn compare_times [s] (cond (< (timeParse s) deadline) "before" "after"))
+
+(defn epoch_roundtrip [s]
+  (def ts (timeParse s))
+  (sprintf "%d %s" ts (timeFormat ts "2006-01-02T15:04:05Z07:00" "UTC")))
+`)
+	MustSuccess(t, err)
+	for _, w := range timeWorkloads {
+		v, _ := env.FindObject(w.fn)
+		fn := v.(*zygo.SexpFunction)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				res, err := env.Apply(fn, []zygo.Sexp{&zygo.SexpStr{S: w.arg}})
+				MustSuccess(t, err)
+				s, ok := res.(*zygo.SexpStr)
+				MustTrue(t, ok)
+				MustEqual(t, w.expect, s.S)
+			}
+		})
+	}
+}
+
+// locations caches the time zones formatUnix loads, since
+// time.LoadLocation reads the zoneinfo database on every call.
+var locations sync.Map
+
+// parseUnix, formatUnix and addDateUnix back the time helpers registered
+// for Lua and zygo, which pass times around as Unix seconds.
+func parseUnix(s string) (int64, error) {
+	tm, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return tm.Unix(), nil
+}
+
+func formatUnix(ts int64, layout, tz string) (string, error) {
+	loc, ok := locations.Load(tz)
+	if !ok {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return "", err
+		}
+		loc, _ = locations.LoadOrStore(tz, l)
+	}
+	return time.Unix(ts, 0).In(loc.(*time.Location)).Format(layout), nil
+}
+
+func addDateUnix(ts int64, years, months, days int) int64 {
+	return time.Unix(ts, 0).UTC().AddDate(years, months, days).Unix()
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/Shopify/go-lua"
+	"github.com/dop251/goja"
+	"github.com/glycerine/zygomys/v9/zygo"
+	"github.com/qjpcpu/glisp"
+	ext "github.com/qjpcpu/glisp/extensions"
+)
+
+// Workloads can also live outside Go as files under testdata/<name>:
+//
+//	manifest.json  entry function, arguments and expected result
+//	script.lisp    glisp
+//	script.js      goja
+//	script.lua     go-lua
+//	script.zy      zygo
+//
+// An engine without a script file is skipped for that workload, and
+// directories without a manifest (such as the fuzz corpus) are ignored.
+// The ports of Benchmarks Game programs live the same way under
+// testdata/macro and run as the Macro benchmark.
+//
+//go:embed testdata
+var testdataFS embed.FS
+
+// workloadSets are the benchmarks running testdata workloads, as
+// sub-benchmarks named after their directories, and where they load them.
+var workloadSets = []struct {
+	benchmark string
+	dir       string
+}{
+	{"Workloads", "testdata"},
+	{"Macro", "testdata/macro"},
+}
+
+// workloadDir returns the directory of the workloads run by benchmark.
+func workloadDir(benchmark string) (string, bool) {
+	for _, set := range workloadSets {
+		if set.benchmark == benchmark {
+			return set.dir, true
+		}
+	}
+	return "", false
+}
+
+var workloadEngines = []string{"glisp", "goja", "lua", "zygo"}
+
+var workloadScripts = map[string]string{
+	"glisp": "script.lisp",
+	"goja":  "script.js",
+	"lua":   "script.lua",
+	"zygo":  "script.zy",
+}
+
+type workloadManifest struct {
+	Description string            `json:"description"`
+	Entry       string            `json:"entry"`
+	Args        []json.RawMessage `json:"args"`
+	Expect      json.RawMessage   `json:"expect"`
+}
+
+// workload is one testdata directory. args and expect are kept as decoded
+// JSON values; results from every engine are compared as formatted by
+// formatResult.
+type workload struct {
+	name        string
+	description string
+	entry       string
+	args        []interface{}
+	expect      string
+	scripts     map[string]string
+}
+
+func loadWorkloads(fsys fs.FS, parent string) ([]workload, error) {
+	dirs, err := fs.ReadDir(fsys, parent)
+	if err != nil {
+		return nil, err
+	}
+	var workloads []workload
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		root := path.Join(parent, dir.Name())
+		raw, err := fs.ReadFile(fsys, path.Join(root, "manifest.json"))
+		if err != nil {
+			continue
+		}
+		w, err := parseManifest(dir.Name(), raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", root, err)
+		}
+		for _, engine := range workloadEngines {
+			src, err := fs.ReadFile(fsys, path.Join(root, workloadScripts[engine]))
+			if err == nil {
+				w.scripts[engine] = string(src)
+			}
+		}
+		workloads = append(workloads, w)
+	}
+	sort.Slice(workloads, func(i, j int) bool { return workloads[i].name < workloads[j].name })
+	return workloads, nil
+}
+
+// eachWorkload calls fn for every testdata workload, macro ones included,
+// whose name matches filter, in every engine of engines that has a script
+// for it. A nil filter and an empty engine set select everything.
+func eachWorkload(filter *regexp.Regexp, engines map[string]bool, fn func(w workload, engine string)) error {
+	for _, set := range workloadSets {
+		workloads, err := loadWorkloads(testdataFS, set.dir)
+		if err != nil {
+			return err
+		}
+		for _, w := range workloads {
+			if filter != nil && !filter.MatchString(w.name) {
+				continue
+			}
+			for _, engine := range workloadEngines {
+				if _, ok := w.scripts[engine]; !ok || len(engines) > 0 && !engines[engine] {
+					continue
+				}
+				fn(w, engine)
+			}
+		}
+	}
+	return nil
+}
+
+func parseManifest(name string, raw []byte) (workload, error) {
+	var m workloadManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return workload{}, err
+	}
+	if m.Entry == "" {
+		return workload{}, fmt.Errorf("manifest has no entry")
+	}
+	w := workload{name: name, description: m.Description, entry: m.Entry, scripts: make(map[string]string)}
+	for _, arg := range m.Args {
+		v, err := decodeJSONValue(arg)
+		if err != nil {
+			return workload{}, err
+		}
+		w.args = append(w.args, v)
+	}
+	expect, err := decodeJSONValue(m.Expect)
+	if err != nil {
+		return workload{}, err
+	}
+	w.expect = formatResult(expect)
+	return w, nil
+}
+
+// decodeJSONValue accepts the scalar types every engine can take as an
+// argument: integers (int64), other numbers (float64), strings and bools.
+func decodeJSONValue(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case string, bool:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported value %s", raw)
+}
+
+// formatResult renders a result the same way for every engine, so that
+// 42 from glisp and 42.0 from Lua compare equal.
+func formatResult(v interface{}) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprint(v)
+}
+
+// prepare loads the workload script into a fresh engine and returns a
+// function calling its entry once with the manifest arguments.
+func (w workload) prepare(engine string) (func() (string, error), error) {
+	src, ok := w.scripts[engine]
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s script", w.name, engine)
+	}
+	switch engine {
+	case "glisp":
+		return w.prepareGlisp(src)
+	case "goja":
+		return w.prepareGoja(src)
+	case "lua":
+		return w.prepareLua(src)
+	case "zygo":
+		return w.prepareZygo(src)
+	}
+	return nil, fmt.Errorf("unknown engine %s", engine)
+}
+
+func (w workload) prepareGlisp(src string) (func() (string, error), error) {
+	vm := glisp.New()
+	if err := ext.ImportAll(vm); err != nil {
+		return nil, err
+	}
+	vm.AddFunction("sqrt", func(env *glisp.Environment, args glisp.Args) (glisp.Sexp, error) {
+		if args.Len() != 1 {
+			return glisp.WrongNumberArguments("sqrt", args.Len(), 1)
+		}
+		switch x := args.Get(0).(type) {
+		case glisp.SexpFloat:
+			return glisp.NewSexpFloat(math.Sqrt(x.ToFloat64())), nil
+		case glisp.SexpInt:
+			return glisp.NewSexpFloat(math.Sqrt(float64(x.ToInt64()))), nil
+		}
+		return glisp.SexpNull, fmt.Errorf("sqrt takes a number")
+	})
+	if err := vm.SourceStream(bytes.NewBufferString(src)); err != nil {
+		return nil, err
+	}
+	args := make([]glisp.Sexp, len(w.args))
+	for i, arg := range w.args {
+		switch arg := arg.(type) {
+		case int64:
+			args[i] = glisp.NewSexpInt64(arg)
+		case float64:
+			args[i] = glisp.NewSexpFloat(arg)
+		case string:
+			args[i] = glisp.SexpStr(arg)
+		case bool:
+			args[i] = glisp.SexpBool(arg)
+		}
+	}
+	return func() (string, error) {
+		v, err := vm.ApplyByName(w.entry, glisp.MakeArgs(args...))
+		if err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case glisp.SexpInt:
+			return formatResult(v.ToInt64()), nil
+		case glisp.SexpFloat:
+			return formatResult(v.ToFloat64()), nil
+		case glisp.SexpStr:
+			return string(v), nil
+		case glisp.SexpBool:
+			return formatResult(bool(v)), nil
+		}
+		return v.SexpString(), nil
+	}, nil
+}
+
+func (w workload) prepareGoja(src string) (func() (string, error), error) {
+	vm := goja.New()
+	if _, err := vm.RunString(src); err != nil {
+		return nil, err
+	}
+	f, ok := goja.AssertFunction(vm.Get(w.entry))
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", w.entry)
+	}
+	args := make([]goja.Value, len(w.args))
+	for i, arg := range w.args {
+		args[i] = vm.ToValue(arg)
+	}
+	return func() (string, error) {
+		res, err := f(goja.Undefined(), args...)
+		if err != nil {
+			return "", err
+		}
+		return formatResult(res.Export()), nil
+	}, nil
+}
+
+func (w workload) prepareLua(src string) (func() (string, error), error) {
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	if err := lua.DoString(l, src); err != nil {
+		return nil, err
+	}
+	return func() (string, error) {
+		l.Global(w.entry)
+		for _, arg := range w.args {
+			switch arg := arg.(type) {
+			case int64:
+				l.PushInteger(int(arg))
+			case float64:
+				l.PushNumber(arg)
+			case string:
+				l.PushString(arg)
+			case bool:
+				l.PushBoolean(arg)
+			}
+		}
+		if err := l.ProtectedCall(len(w.args), 1, 0); err != nil {
+			l.Pop(1)
+			return "", err
+		}
+		defer l.Pop(1)
+		switch l.TypeOf(-1) {
+		case lua.TypeNumber:
+			n, _ := l.ToNumber(-1)
+			return formatResult(n), nil
+		case lua.TypeBoolean:
+			return formatResult(l.ToBoolean(-1)), nil
+		case lua.TypeNil:
+			return formatResult(nil), nil
+		}
+		s, _ := l.ToString(-1)
+		return s, nil
+	}, nil
+}
+
+func (w workload) prepareZygo(src string) (func() (string, error), error) {
+	env := zygo.NewZlisp()
+	env.ImportRegex()
+	env.AddFunction("sqrt", func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+		if len(args) != 1 {
+			return zygo.SexpNull, zygo.WrongNargs
+		}
+		switch x := args[0].(type) {
+		case *zygo.SexpFloat:
+			return &zygo.SexpFloat{Val: math.Sqrt(x.Val)}, nil
+		case *zygo.SexpInt:
+			return &zygo.SexpFloat{Val: math.Sqrt(float64(x.Val))}, nil
+		}
+		return zygo.SexpNull, fmt.Errorf("sqrt takes a number")
+	})
+	if _, err := env.EvalString(src); err != nil {
+		return nil, err
+	}
+	v, ok := env.FindObject(w.entry)
+	if !ok {
+		return nil, fmt.Errorf("%s not found", w.entry)
+	}
+	fn, ok := v.(*zygo.SexpFunction)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", w.entry)
+	}
+	args := make([]zygo.Sexp, len(w.args))
+	for i, arg := range w.args {
+		switch arg := arg.(type) {
+		case int64:
+			args[i] = &zygo.SexpInt{Val: arg}
+		case float64:
+			args[i] = &zygo.SexpFloat{Val: arg}
+		case string:
+			args[i] = &zygo.SexpStr{S: arg}
+		case bool:
+			args[i] = &zygo.SexpBool{Val: arg}
+		}
+	}
+	return func() (string, error) {
+		res, err := env.Apply(fn, args)
+		if err != nil {
+			return "", err
+		}
+		switch res := res.(type) {
+		case *zygo.SexpInt:
+			return formatResult(res.Val), nil
+		case *zygo.SexpFloat:
+			return formatResult(res.Val), nil
+		case *zygo.SexpStr:
+			return res.S, nil
+		case *zygo.SexpBool:
+			return formatResult(res.Val), nil
+		}
+		return res.SexpString(nil), nil
+	}, nil
+}
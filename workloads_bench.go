@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+// benchmarkWorkloads turns every workload under dir with a script for
+// engine into a sub-benchmark named after its directory.
+func benchmarkWorkloads(t *testing.B, dir, engine string) {
+	workloads, err := loadWorkloads(testdataFS, dir)
+	MustSuccess(t, err)
+	for _, w := range workloads {
+		if _, ok := w.scripts[engine]; !ok {
+			continue
+		}
+		call, err := w.prepare(engine)
+		MustSuccess(t, err)
+		subBenchmark(t, w.name, func(t *testing.B) {
+			for i := 0; i < t.N; i++ {
+				got, err := call()
+				MustSuccess(t, err)
+				MustEqual(t, w.expect, got)
+			}
+		})
+	}
+}
+
+func benchWorkloads_glisp(t *testing.B) {
+	benchmarkWorkloads(t, "testdata", "glisp")
+}
+
+func benchWorkloads_goja(t *testing.B) {
+	benchmarkWorkloads(t, "testdata", "goja")
+}
+
+func benchWorkloads_lua(t *testing.B) {
+	benchmarkWorkloads(t, "testdata", "lua")
+}
+
+func benchWorkloads_zygo(t *testing.B) {
+	benchmarkWorkloads(t, "testdata", "zygo")
+}
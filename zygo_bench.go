@@ -3,11 +3,12 @@ package main
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/glycerine/zygomys/v9/zygo"
 )
 
-func BenchmarkFactorial_zygo(t *testing.B) {
+func benchFactorial_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	_, err := env.EvalString(`(defn factorial[n]
 (cond (== 1 n) n (* n (factorial (- n 1))))
@@ -22,7 +23,7 @@ func BenchmarkFactorial_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkRegexpMatch_zygo(t *testing.B) {
+func benchRegexpMatch_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	env.ImportRegex()
 	_, err := env.EvalString(`
@@ -41,7 +42,7 @@ func BenchmarkRegexpMatch_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkComplexCondition_zygo(t *testing.B) {
+func benchComplexCondition_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	_, err := env.EvalString(`
 (defn complex_condition [n]
@@ -63,7 +64,42 @@ func BenchmarkComplexCondition_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkHashWrite_zygo(t *testing.B) {
+func benchFormatTime_zygo(t *testing.B) {
+	env := zygo.NewZlisp()
+	env.AddFunction("format",
+		func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {
+			if len(args) != 3 {
+				return zygo.SexpNull, zygo.WrongNargs
+			}
+			val, ok1 := args[0].(*zygo.SexpStr)
+			layout, ok2 := args[1].(*zygo.SexpStr)
+			newLayout, ok3 := args[2].(*zygo.SexpStr)
+			if !ok1 || !ok2 || !ok3 {
+				return zygo.SexpNull, fmt.Errorf("arguments of %v should be strings", name)
+			}
+			t, err := time.Parse(layout.S, val.S)
+			if err != nil {
+				return zygo.SexpNull, err
+			}
+			return &zygo.SexpStr{S: t.Format(newLayout.S)}, nil
+		})
+	_, err := env.EvalString(`
+(defn formatTime [t]
+  (format t "2006-01-02T15:04:05Z" "2006年01月02日 15时04分05秒"))
+`)
+	MustSuccess(t, err)
+	v, _ := env.FindObject("formatTime")
+	fn := v.(*zygo.SexpFunction)
+	for i := 0; i < t.N; i++ {
+		res, err := env.Apply(fn, []zygo.Sexp{&zygo.SexpStr{S: "2006-01-02T15:04:05Z"}})
+		MustSuccess(t, err)
+		s, ok := res.(*zygo.SexpStr)
+		MustTrue(t, ok)
+		MustEqual(t, "2006年01月02日 15时04分05秒", s.S)
+	}
+}
+
+func benchHashWrite_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	_, err := env.EvalString(`
 (def m (hash
@@ -88,7 +124,7 @@ func BenchmarkHashWrite_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkHashDelete_zygo(t *testing.B) {
+func benchHashDelete_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	_, err := env.EvalString(`
 (def m (hash
@@ -113,7 +149,7 @@ func BenchmarkHashDelete_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkHashAccess_zygo(t *testing.B) {
+func benchHashAccess_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	_, err := env.EvalString(`
 (def m (hash
@@ -139,7 +175,7 @@ func BenchmarkHashAccess_zygo(t *testing.B) {
 	}
 }
 
-func BenchmarkJSONParseAndModify_zygo(t *testing.B) {
+func benchJSONParseAndModify_zygo(t *testing.B) {
 	env := zygo.NewZlisp()
 	env.AddFunction("parseJSON",
 		func(env *zygo.Zlisp, name string, args []zygo.Sexp) (zygo.Sexp, error) {